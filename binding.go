@@ -0,0 +1,583 @@
+package hapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lovego/struct_tag"
+)
+
+// structBinding is the binding of a struct type like req.Query, compiled once so that binding a request
+// is a loop over its fields without parsing tags or switching on kinds.
+//
+// The params and form values are also bound by keys like "filter[status]", "filter[owner][id]" or
+// "filter.owner.id": into the nested struct fields, the maps with string keys, keyed by "status" or
+// "owner.id", and the map field tagged `query:",remain"` or `json:",remain"`, which gets the params of no other field.
+type structBinding struct {
+	fields     []fieldBinding
+	structured bool // has nested, map or remain fields
+	defaults   bool // has default tags, including in its nested structs
+	remain     int  // index of the remain field in fields, -1 if there is none
+	// keys are the indexes in fields by the lowercased key and arrayKey of the fields,
+	// to find the values of a field whose key differs in case without scanning all the values.
+	keys map[string]int
+}
+
+type fieldBinding struct {
+	index       []int         // of the field, through the embedded structs
+	name        string        // of the field, e.g. "Page"
+	typ         reflect.Type  // of the field
+	key         string        // of the param, header or cookie, empty for a field not bound
+	arrayKey    string        // key+"[]" of a slice or array field named by its json tag
+	array       bool          // of a slice or array field, set from all the values of its key
+	sep         string        // of the split tag option, which splits the values of a slice or array field
+	def         []string      // the default tag as values, nil if there is none
+	file        reflect.Type  // typeFileHeader or typeFileHeaderSlice for a field set from the uploaded files
+	cookie      *http.Cookie  // described by the cookie tag of a resp.Cookie string field
+	expires     time.Duration // of the expires attribute of cookie, from the time it is written
+	nested      *structBinding
+	set         func(v reflect.Value, s string) error
+	setArray    func(v reflect.Value, array []string) error
+	setMapIndex func(v reflect.Value, key string, array []string) error // of a map field with string keys
+}
+
+// structBindings caches the bindings of struct types by the tag naming their fields:
+// "json" for the params, query and form values, "header" or "cookie".
+var structBindings = map[string]*sync.Map{"json": {}, "header": {}, "cookie": {}}
+
+// bindingOf returns the binding of typ, or of the struct it points to, compiling it on the first call.
+func bindingOf(typ reflect.Type, tag string) *structBinding {
+	typ = indirectType(typ)
+	cache := structBindings[tag]
+	if b, ok := cache.Load(typ); ok {
+		return b.(*structBinding)
+	}
+	actual, _ := cache.LoadOrStore(typ, compileBinding(typ, tag, map[reflect.Type]bool{}))
+	return actual.(*structBinding)
+}
+
+// compileBinding compiles the binding of typ, a struct, visiting are the types of the structs it is nested in,
+// whose fields are not bound as nested structs again.
+func compileBinding(typ reflect.Type, tag string, visiting map[reflect.Type]bool) *structBinding {
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	b := &structBinding{remain: -1}
+	traverseTypeIndex(typ, nil, func(f reflect.StructField, index []int) {
+		fb := newFieldBinding(f, index, tag)
+		if tag == "json" && fb.file == nil {
+			if nested := indirectType(f.Type); nested.Kind() == reflect.Struct && !visiting[nested] && !isUnmarshaler(nested) {
+				fb.nested = compileBinding(nested, tag, visiting)
+			} else if f.Type.Kind() == reflect.Map && f.Type.Key().Kind() == reflect.String && !isUnmarshaler(f.Type) {
+				fb.setMapIndex = compileSetMapIndex(f.Type)
+			}
+			if tagHasOption(f.Tag.Get("query"), "remain") || tagHasOption(f.Tag.Get("json"), "remain") {
+				if fb.setMapIndex == nil || b.remain >= 0 {
+					panic(f.Name + `: a ",remain" field must be the only one of its struct and a map with string keys.`)
+				}
+				b.remain = len(b.fields)
+				fb.key, fb.arrayKey = "", ""
+			}
+			b.structured = b.structured || fb.nested != nil || fb.setMapIndex != nil
+		}
+		b.defaults = b.defaults || fb.def != nil || fb.nested != nil && fb.nested.defaults
+		b.addKey(fb.key)
+		b.addKey(fb.arrayKey)
+		b.fields = append(b.fields, fb)
+	})
+	return b
+}
+
+// addKey indexes the field to be appended to b.fields by key, unless another field has the key.
+func (b *structBinding) addKey(key string) {
+	if key == "" {
+		return
+	}
+	if b.keys == nil {
+		b.keys = make(map[string]int)
+	}
+	key = strings.ToLower(key)
+	if _, ok := b.keys[key]; !ok {
+		b.keys[key] = len(b.fields)
+	}
+}
+
+// fieldValues sets found, by the index of the fields, to the values of the fields from values: the values
+// of its key or array key, otherwise those of a key differing in case. values is looped over once at most,
+// and not at all when each of its keys is that of a field or when every field has values.
+func fieldValues[T any](b *structBinding, values map[string][]T, found [][]T) {
+	exact, missing := 0, false
+	for i := range b.fields {
+		f := &b.fields[i]
+		if f.key == "" {
+			continue
+		}
+		if array, ok := values[f.key]; ok {
+			found[i] = array
+			exact++
+		} else if array, ok := values[f.arrayKey]; ok && f.arrayKey != "" {
+			found[i] = array
+			exact++
+		} else {
+			missing = true
+		}
+	}
+	if !missing || exact >= len(values) {
+		return
+	}
+	for key, array := range values {
+		if i, ok := b.keys[strings.ToLower(key)]; ok && found[i] == nil {
+			found[i] = array
+		}
+	}
+}
+
+var (
+	typeJSONUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	typeTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isUnmarshaler reports whether a value of typ unmarshals itself, like time.Time, so it is not bound as a struct or map.
+func isUnmarshaler(typ reflect.Type) bool {
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(typeJSONUnmarshaler) || ptr.Implements(typeTextUnmarshaler)
+}
+
+// tagHasOption reports whether option is one of the options after the name in tag, like "remain" in ",remain".
+func tagHasOption(tag, option string) bool {
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		for _, opt := range strings.Split(tag[i+1:], ",") {
+			if opt == option {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newFieldBinding(f reflect.StructField, index []int, tag string) fieldBinding {
+	kind := indirectType(f.Type).Kind()
+	fb := fieldBinding{
+		index: index, name: f.Name, typ: f.Type, array: kind == reflect.Slice || kind == reflect.Array,
+		set: compileSet(f.Type), setArray: compileSetArray(f.Type),
+	}
+	var options string
+	switch tag {
+	case "json":
+		fb.key, fb.arrayKey = queryParamName(f)
+		options = f.Tag.Get("query")
+		if f.Type == typeFileHeader || f.Type == typeFileHeaderSlice {
+			fb.file = f.Type
+		}
+	case "header":
+		fb.key, _ = struct_tag.Lookup(string(f.Tag), "header")
+		options = fb.key
+	case "cookie":
+		fb.key, _ = struct_tag.Lookup(string(f.Tag), "cookie")
+		if f.Type.Kind() == reflect.String {
+			fb.cookie, fb.expires, _ = respCookie(f) // the tag of a resp.Cookie is checked by ValidateRespCookie
+		}
+	}
+	if tag != "json" {
+		if i := strings.IndexByte(fb.key, ','); i >= 0 {
+			fb.key = fb.key[:i]
+		}
+		if fb.key == "" {
+			fb.key = f.Name
+		}
+	}
+
+	if i := strings.Index(options, ",split="); i >= 0 {
+		if fb.sep = options[i+len(",split="):]; fb.sep == "" {
+			panic(f.Name + ": the separator of split can not be empty.")
+		}
+		if !fb.array {
+			panic(f.Name + ": split only applies to slice and array fields.")
+		}
+	}
+	if def := f.Tag.Get("default"); def != "" {
+		fb.def = []string{def}
+		if fb.sep != "" {
+			fb.def = splitValues(fb.def, fb.sep)
+		}
+	}
+	return fb
+}
+
+// splitValues splits each of values by sep, trimming the spaces around the parts and dropping the empty ones.
+func splitValues(values []string, sep string) []string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, part := range strings.Split(value, sep) {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
+
+// bindParams sets the fields of v, a struct, from the URL params.
+func (b *structBinding) bindParams(v reflect.Value, params Params) error {
+	if len(params) == 0 {
+		return nil
+	}
+	for i := range b.fields {
+		f := &b.fields[i]
+		if f.key == "" {
+			continue
+		}
+		field := fieldByIndex(v, f.index)
+		if s := pathParamValue(params, f.key); s != "" {
+			if err := f.set(field, s); err != nil {
+				return newBindingError("param", "req.Param."+f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// bindValues sets the fields of v, a struct, from values and files, and from their default tag if defaults is true.
+// The errors are reported for location and the fields are named with prefix.
+func (b *structBinding) bindValues(
+	v reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader,
+	defaults bool, location, prefix string,
+) error {
+	if len(values) == 0 && len(files) == 0 && !defaults {
+		return nil
+	}
+	var buf [16][]string
+	found := buf[:]
+	if len(b.fields) > len(buf) {
+		found = make([][]string, len(b.fields))
+	}
+	if len(values) > 0 {
+		fieldValues(b, values, found)
+	}
+	var foundFiles [][]*multipart.FileHeader
+	if len(files) > 0 {
+		foundFiles = make([][]*multipart.FileHeader, len(b.fields))
+		fieldValues(b, files, foundFiles)
+	}
+	for i := range b.fields {
+		f := &b.fields[i]
+		if f.key == "" {
+			continue
+		}
+		field := fieldByIndex(v, f.index)
+		if f.file != nil {
+			if len(foundFiles) == 0 {
+				continue
+			}
+			if fhs := foundFiles[i]; len(fhs) > 0 {
+				if f.file == typeFileHeader {
+					field.Set(reflect.ValueOf(fhs[0]))
+				} else {
+					field.Set(reflect.ValueOf(fhs))
+				}
+			}
+			continue
+		}
+		// field is always empty, so set it only when len(values) > 0
+		values := found[i]
+		if f.sep != "" && len(values) > 0 {
+			values = splitValues(values, f.sep)
+		}
+		if len(values) == 0 && defaults {
+			values = f.def
+		}
+		if len(values) > 0 {
+			if err := f.setArray(field, values); err != nil {
+				return newBindingError(location, prefix+f.name, err)
+			}
+		}
+	}
+	if b.structured {
+		return b.bindStructured(v, values, defaults, location, prefix)
+	}
+	return nil
+}
+
+// bindStructured sets the nested struct, map and remain fields of v from the values not bound to the other fields.
+func (b *structBinding) bindStructured(
+	v reflect.Value, values map[string][]string, defaults bool, location, prefix string,
+) error {
+	var nested map[int]map[string][]string // the values of the nested struct fields, by the keys relative to them
+	for key, array := range values {
+		if b.boundField(key) {
+			continue
+		}
+		i := -1
+		if head, rest, ok := cutParamKey(key); ok {
+			if i = b.structuredField(head); i >= 0 && b.fields[i].nested != nil {
+				if nested == nil {
+					nested = make(map[int]map[string][]string)
+				}
+				if nested[i] == nil {
+					nested[i] = make(map[string][]string)
+				}
+				nested[i][rest] = array
+				continue
+			} else if i >= 0 {
+				key = rest
+			}
+		}
+		if i < 0 {
+			if i = b.remain; i < 0 {
+				continue
+			}
+		}
+		key = strings.TrimSuffix(key, "[]")
+		f := &b.fields[i]
+		if err := f.setMapIndex(fieldByIndex(v, f.index), key, array); err != nil {
+			return newBindingError(location, prefix+f.name+"["+key+"]", err)
+		}
+	}
+	for i := range b.fields {
+		f := &b.fields[i]
+		if f.nested == nil || len(nested[i]) == 0 && !(defaults && f.nested.defaults) {
+			continue
+		}
+		if err := f.nested.bindValues(
+			indirectStruct(fieldByIndex(v, f.index)), nested[i], nil, defaults, location, prefix+f.name+".",
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boundField reports whether key is the param of a field, which is bound by its name.
+func (b *structBinding) boundField(key string) bool {
+	_, ok := b.keys[strings.ToLower(key)]
+	return ok
+}
+
+// structuredField returns the index of the nested struct or map field named head, -1 if there is none.
+func (b *structBinding) structuredField(head string) int {
+	if i, ok := b.keys[strings.ToLower(head)]; ok && (b.fields[i].nested != nil || b.fields[i].setMapIndex != nil) {
+		return i
+	}
+	return -1
+}
+
+// cutParamKey cuts a key like "filter[owner][id]", "filter[owner.id]" or "filter.owner.id" into the param
+// of a field, "filter", and the key relative to it in dot notation, "owner.id". A trailing "[]" is kept.
+func cutParamKey(key string) (head, rest string, ok bool) {
+	i := strings.IndexAny(key, "[.")
+	if i <= 0 {
+		return "", "", false
+	}
+	head, rest = key[:i], key[i:]
+	array := strings.HasSuffix(rest, "[]")
+	if array {
+		rest = rest[:len(rest)-2]
+	}
+	if strings.ContainsAny(rest, "[]") {
+		rest = strings.NewReplacer("[", ".", "]", "").Replace(rest)
+	}
+	if rest = strings.TrimLeft(rest, "."); rest == "" {
+		return "", "", false
+	}
+	if array {
+		rest += "[]"
+	}
+	return head, rest, true
+}
+
+// validateDefaults checks that the default tags of the fields of b, and of its nested structs, can be set to them.
+func (b *structBinding) validateDefaults(prefix string) {
+	for i := range b.fields {
+		f := &b.fields[i]
+		if f.def != nil {
+			if err := f.setArray(reflect.New(f.typ).Elem(), f.def); err != nil {
+				panic(prefix + f.name + ": invalid default '" + f.def[0] + "': " + err.Error())
+			}
+		}
+		if f.nested != nil {
+			f.nested.validateDefaults(prefix + f.name + ".")
+		}
+	}
+}
+
+// bindHeader sets the fields of v, a struct, from the first value of their header, or their default tag.
+// A slice or array field is set from all the values, split by its split option if any.
+func (b *structBinding) bindHeader(v reflect.Value, header map[string][]string) error {
+	for i := range b.fields {
+		f := &b.fields[i]
+		field := fieldByIndex(v, f.index)
+		values := header[f.key]
+		if f.array {
+			if f.sep != "" {
+				values = splitValues(values, f.sep)
+			}
+			if len(values) == 0 {
+				values = f.def
+			}
+			if len(values) > 0 {
+				if err := f.setArray(field, values); err != nil {
+					return newBindingError("header", "req.Header."+f.name, err)
+				}
+			}
+			continue
+		}
+		var s string
+		if len(values) > 0 {
+			s = values[0]
+		}
+		if s == "" && f.def != nil {
+			s = f.def[0]
+		}
+		if s != "" {
+			if err := f.set(field, s); err != nil {
+				return newBindingError("header", "req.Header."+f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// bindCookies sets the fields of v, a struct, from the first non-empty cookie of their name, or their default tag.
+func (b *structBinding) bindCookies(v reflect.Value, cookies []*http.Cookie) error {
+	for i := range b.fields {
+		f := &b.fields[i]
+		field := fieldByIndex(v, f.index)
+		var s string
+		for _, cookie := range cookies {
+			if cookie.Name == f.key && cookie.Value != "" {
+				s = cookie.Value
+				break
+			}
+		}
+		if s == "" && f.def != nil {
+			s = f.def[0]
+		}
+		if s != "" {
+			if err := f.set(field, s); err != nil {
+				return newBindingError("cookie", "req.Cookie."+f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeHeader sets the headers of the non-empty fields of v, a resp.Header struct.
+func (b *structBinding) writeHeader(v reflect.Value, header http.Header) {
+	for i := range b.fields {
+		f := &b.fields[i]
+		if s := fieldByIndex(v, f.index).String(); s != "" {
+			header.Set(f.key, s)
+		}
+	}
+}
+
+// writeCookies sets the cookies of the non-empty fields of v, a resp.Cookie struct.
+func (b *structBinding) writeCookies(v reflect.Value, w http.ResponseWriter) {
+	for i := range b.fields {
+		f := &b.fields[i]
+		field := fieldByIndex(v, f.index)
+		if f.cookie == nil {
+			if cookie := field.Interface().(*http.Cookie); cookie != nil {
+				http.SetCookie(w, cookie)
+			}
+		} else if s := field.String(); s != "" {
+			cookie := *f.cookie
+			cookie.Value = s
+			if f.expires > 0 {
+				cookie.Expires = time.Now().Add(f.expires)
+			}
+			http.SetCookie(w, &cookie)
+		}
+	}
+}
+
+// reqSection is the compiled binding of a field of req, like Query.
+type reqSection struct {
+	index []int
+	bind  func(v reflect.Value, ctx *Context) error
+}
+
+// compileReqSections compiles the binding of the fields of req type typ which are to be bound according to todo.
+func compileReqSections(typ reflect.Type, todo todoReqFields) (sections []reqSection) {
+	traverseTypeIndex(typ, nil, func(f reflect.StructField, index []int) {
+		var bind func(reflect.Value, *Context) error
+		switch f.Name {
+		case "Param":
+			if todo.Param {
+				b := bindingOf(f.Type, "json")
+				bind = func(v reflect.Value, ctx *Context) error {
+					return b.bindParams(indirectStruct(v), ctx.Params)
+				}
+			}
+		case "Query":
+			if todo.Query {
+				b := bindingOf(f.Type, "json")
+				bind = func(v reflect.Value, ctx *Context) error {
+					return b.bindValues(indirectStruct(v), ctx.Request.URL.Query(), nil, true, "query", "req.Query.")
+				}
+			}
+		case "Header":
+			if todo.Header {
+				b := bindingOf(f.Type, "header")
+				bind = func(v reflect.Value, ctx *Context) error {
+					return b.bindHeader(indirectStruct(v), ctx.Request.Header)
+				}
+			}
+		case "Cookie":
+			if todo.Cookie {
+				b := bindingOf(f.Type, "cookie")
+				bind = func(v reflect.Value, ctx *Context) error {
+					return b.bindCookies(indirectStruct(v), ctx.Request.Cookies())
+				}
+			}
+		case "Body":
+			if todo.Body {
+				var form *structBinding // nil if the body can't be bound from a form
+				if indirectType(f.Type).Kind() == reflect.Struct {
+					form = bindingOf(f.Type, "json")
+				}
+				bind = func(v reflect.Value, ctx *Context) error {
+					return convertReqBody(v, ctx, form)
+				}
+			}
+		case "Ctx":
+			if todo.Ctx {
+				bind = func(v reflect.Value, ctx *Context) error {
+					v.Set(reflect.ValueOf(ctx))
+					return nil
+				}
+			}
+		}
+		if bind != nil {
+			sections = append(sections, reqSection{index: index, bind: bind})
+		}
+	})
+	return
+}
+
+// respField is a field of resp, Error, Data, Header or Cookie, written by the resp write func.
+type respField struct {
+	index   []int
+	name    string
+	binding *structBinding // of Header or Cookie
+}
+
+func compileRespFields(typ reflect.Type) (fields []respField) {
+	traverseTypeIndex(typ, nil, func(f reflect.StructField, index []int) {
+		field := respField{index: index, name: f.Name}
+		switch f.Name {
+		case "Header":
+			field.binding = bindingOf(f.Type, "header")
+		case "Cookie":
+			field.binding = bindingOf(f.Type, "cookie")
+		}
+		fields = append(fields, field)
+	})
+	return
+}
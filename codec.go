@@ -0,0 +1,128 @@
+package hapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Codec decodes the request bodies and encodes the response bodies of a media type.
+type Codec interface {
+	// ContentType is the Content-Type header of the responses encoded by the codec.
+	ContentType() string
+	Decode(body []byte, v interface{}) error
+	Encode(w io.Writer, v interface{}) error
+}
+
+const mimeJSON = "application/json"
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (jsonCodec) Decode(body []byte, v interface{}) error {
+	return json.Unmarshal(body, v)
+}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (xmlCodec) Decode(body []byte, v interface{}) error {
+	return xml.Unmarshal(body, v)
+}
+
+func (xmlCodec) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+var defaultCodecs = map[string]Codec{
+	mimeJSON:          jsonCodec{},
+	"application/xml": xmlCodec{},
+	"text/xml":        xmlCodec{},
+}
+
+// Codec registers codec for mediaType, e.g. "application/x-protobuf". The request bodies of this
+// Content-Type, or of a type with its structured syntax suffix like "+xml", are decoded by codec, and
+// the responses written by Context.Data are encoded by it when the request prefers the media type.
+// JSON is used for the requests without Content-Type and for the other responses, a codec registered
+// for "application/json" replaces the default one, including for Context.StatusJson.
+// Codecs can't be registered once the engine is frozen.
+func (engine *Engine) Codec(mediaType string, codec Codec) {
+	if engine.frozen {
+		panic("can not register codec of '" + mediaType + "': the engine is frozen")
+	}
+	assert1(codec != nil, "codec of '"+mediaType+"' can not be nil")
+	parsed, params, err := mime.ParseMediaType(mediaType)
+	if err != nil || len(params) > 0 || strings.Contains(parsed, "*") {
+		panic("invalid media type '" + mediaType + "'")
+	}
+	engine.codecs[parsed] = codec
+}
+
+// codec returns the codec decoding the request bodies of mediaType, the one of mediaType or of its
+// structured syntax suffix like "+json", nil if there is none.
+func (engine *Engine) codec(mediaType string) Codec {
+	if codec := engine.codecs[mediaType]; codec != nil {
+		return codec
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i > 0 {
+		return engine.codecs["application/"+mediaType[i+1:]]
+	}
+	return nil
+}
+
+// responseCodec returns the codec of the media type preferred by the Accept header of the request:
+// the registered codec of a media type having the highest q value, higher than the one of "*/*" or
+// "application/*" if any. Otherwise, e.g. for the Accept header of a browser preferring text/html,
+// the JSON codec is returned.
+func (c *Context) responseCodec() Codec {
+	engine := c.engine
+	accept := c.Request.Header.Get("Accept")
+	if accept == "" {
+		return engine.codecs[mimeJSON]
+	}
+	var preferred Codec
+	maxQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 || q < maxQ {
+			continue
+		}
+		codec := engine.codecs[mediaType]
+		if mediaType == "*/*" || mediaType == "application/*" {
+			codec = nil // any type is served as JSON
+		}
+		if q > maxQ {
+			maxQ, preferred = q, codec
+		} else if codec == nil || preferred == nil {
+			preferred = nil // a media type as preferred can't be served by a codec
+		}
+	}
+	if preferred == nil {
+		return engine.codecs[mimeJSON]
+	}
+	return preferred
+}
@@ -0,0 +1,127 @@
+package hapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testCodec struct{}
+
+func (testCodec) ContentType() string {
+	return "application/x-test"
+}
+
+func (testCodec) Decode(body []byte, v interface{}) error {
+	_, err := fmt.Sscan(string(body), &v.(*struct{ Name string }).Name)
+	return err
+}
+
+func (testCodec) Encode(w io.Writer, v interface{}) error {
+	_, err := fmt.Fprintf(w, "%v", v.(dataBody).Data)
+	return err
+}
+
+func TestCodec(t *testing.T) {
+	router := New()
+	router.Codec("application/x-test", testCodec{})
+	router.POST("/users", func(req *struct {
+		Body struct{ Name string }
+	}, resp *struct {
+		Data interface{}
+	}) {
+		resp.Data = req.Body
+	})
+
+	tests := []struct {
+		contentType string
+		accept      string
+		body        string
+		code        int
+		respType    string
+		resp        string
+	}{
+		{"", "", `{"Name":"json"}`, http.StatusOK, "application/json; charset=utf-8",
+			`{"code":0,"message":"success","data":{"Name":"json"}}` + "\n"},
+		{"application/xml", "application/xml", `<user><Name>xml</Name></user>`, http.StatusOK, "application/xml; charset=utf-8",
+			`<response><code>0</code><message>success</message><data><Name>xml</Name></data></response>`},
+		{"application/vnd.partner+xml", "application/x-test;q=0.5", `<user><Name>suffix</Name></user>`,
+			http.StatusOK, "application/x-test", `{suffix}`},
+		{"application/xml", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			`<user><Name>browser</Name></user>`, http.StatusOK, "application/json; charset=utf-8",
+			`{"code":0,"message":"success","data":{"Name":"browser"}}` + "\n"},
+		{"application/xml", "application/xhtml+xml", `<user><Name>xhtml</Name></user>`, http.StatusOK,
+			"application/json; charset=utf-8", `{"code":0,"message":"success","data":{"Name":"xhtml"}}` + "\n"},
+		{"", "application/xml, */*", `{"Name":"tie"}`, http.StatusOK, "application/json; charset=utf-8",
+			`{"code":0,"message":"success","data":{"Name":"tie"}}` + "\n"},
+		{"", "application/xml;q=0.9, */*;q=0.8", `{"Name":"xml"}`, http.StatusOK, "application/xml; charset=utf-8",
+			`<response><code>0</code><message>success</message><data><Name>xml</Name></data></response>`},
+		{"application/x-test", "text/html, */*", `test`, http.StatusOK, "application/json; charset=utf-8",
+			`{"code":0,"message":"success","data":{"Name":"test"}}` + "\n"},
+		{"application/yaml", "", `name: yaml`, http.StatusUnsupportedMediaType, "application/json; charset=utf-8",
+			`{"code":1001,"message":"req.Body: unsupported Content-Type 'application/yaml'",` +
+				`"data":[{"field":"req.Body","location":"body","reason":"unsupported Content-Type 'application/yaml'"}]}` + "\n"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		req.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: status code %d", test.contentType, w.Code)
+		}
+		if respType := w.Header().Get("Content-Type"); respType != test.respType {
+			t.Errorf("%s: response Content-Type %q", test.contentType, respType)
+		}
+		if resp := w.Body.String(); resp != test.resp {
+			t.Errorf("%s: response %s", test.contentType, resp)
+		}
+	}
+
+	if recv := catchPanic(func() { router.Codec("application/*", testCodec{}) }); recv == nil {
+		t.Error("no panic for codec of a media range")
+	}
+	router.Freeze()
+	if recv := catchPanic(func() { router.Codec("application/x-other", testCodec{}) }); recv == nil {
+		t.Error("no panic for codec registered once frozen")
+	}
+}
+
+func TestCodecEncodeError(t *testing.T) {
+	router := New()
+	router.GET("/map", func(req *struct{}, resp *struct {
+		Data interface{}
+	}) {
+		resp.Data = map[string]int{"a": 1}
+	})
+	router.GET("/chan", func(c *Context) {
+		c.Data(make(chan int), nil)
+	})
+
+	w := performRequest(router, http.MethodGet, "/map", header{"Accept", "application/xml"})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json; charset=utf-8" ||
+		w.Body.String() != `{"code":0,"message":"success","data":{"a":1}}`+"\n" {
+		t.Errorf("xml fallback: status code %d, Content-Type %q, body %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	w = performRequest(router, http.MethodGet, "/chan", header{"Accept", "application/xml"})
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/json; charset=utf-8" ||
+		w.Body.String() != `{"code":"json-marshal-error","message":"json marshal error"}` {
+		t.Errorf("json error: status code %d, Content-Type %q, body %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
+
+func TestCodecReplacesJSON(t *testing.T) {
+	router := New()
+	router.Codec("application/json", testCodec{})
+	router.GET("/", func(c *Context) {
+		c.StatusJson(http.StatusCreated, dataBody{Data: "replaced"})
+	})
+	w := performRequest(router, http.MethodGet, "/")
+	if w.Code != http.StatusCreated || w.Body.String() != "replaced" {
+		t.Errorf("status code %d, body %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,62 @@
+package hapi
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParamConstraint reports whether value is acceptable for a constrained path parameter,
+// e.g. the "int" in "/orders/:id<int>".
+type ParamConstraint func(value string) bool
+
+var defaultConstraints = map[string]ParamConstraint{
+	"int": func(value string) bool {
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	},
+	"uint": func(value string) bool {
+		_, err := strconv.ParseUint(value, 10, 64)
+		return err == nil
+	},
+	"alpha": regexp.MustCompile(`^[a-zA-Z]+$`).MatchString,
+	"alnum": regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString,
+	"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+}
+
+var constraintNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Constraint registers a named constraint which can then be used in route patterns like "/:id<name>".
+// It must be called before the routes using it are registered.
+func (engine *Engine) Constraint(name string, constraint ParamConstraint) {
+	assert1(constraintNameRegexp.MatchString(name), "invalid constraint name '"+name+"'")
+	assert1(constraint != nil, "constraint '"+name+"' can not be nil")
+	if _, ok := engine.constraints[name]; ok {
+		panic("constraint '" + name + "' is already registered")
+	}
+	engine.constraints[name] = constraint
+}
+
+// splitParamConstraint splits a ":name<constraint>" wildcard into its name and constraint.
+func splitParamConstraint(wildcard string) (name, constraint string) {
+	if i := strings.IndexByte(wildcard, '<'); i > 0 && wildcard[len(wildcard)-1] == '>' {
+		return wildcard[1:i], wildcard[i+1 : len(wildcard)-1]
+	}
+	return wildcard[1:], ""
+}
+
+// compileConstraint resolves the constraint of a route wildcard.
+// A constraint is either a registered name or a regular expression which must match the whole value.
+func compileConstraint(constraint, fullPath string, constraints map[string]ParamConstraint) ParamConstraint {
+	if fn, ok := constraints[constraint]; ok {
+		return fn
+	}
+	if constraintNameRegexp.MatchString(constraint) {
+		panic("unknown constraint '" + constraint + "' in path '" + fullPath + "'")
+	}
+	re, err := regexp.Compile(`^(?:` + constraint + `)$`)
+	if err != nil {
+		panic("invalid constraint '" + constraint + "' in path '" + fullPath + "': " + err.Error())
+	}
+	return re.MatchString
+}
@@ -2,7 +2,7 @@ package hapi
 
 import (
 	"bytes"
-	"encoding/json"
+	"encoding/xml"
 	"io/ioutil"
 	"math"
 	"net"
@@ -30,11 +30,15 @@ type Context struct {
 	// Keys is a key/value pair exclusively for the context of each request.
 	Keys map[string]any
 
-	Writer   ResponseWriter
-	Request  *http.Request
-	fullPath string
-	data     map[string]interface{}
-	err      error
+	Writer  ResponseWriter
+	Request *http.Request
+	// Params is the list of URL parameters matched by the router for this request.
+	Params       Params
+	params       *Params
+	skippedNodes *[]skippedNode
+	fullPath     string
+	data         map[string]interface{}
+	err          error
 }
 
 /************************************/
@@ -44,6 +48,10 @@ type Context struct {
 func (c *Context) reset() {
 	c.Writer = &c.writermem
 	c.handlers = nil
+	c.Keys = nil
+	c.Params = c.Params[:0]
+	*c.params = (*c.params)[:0]
+	*c.skippedNodes = (*c.skippedNodes)[:0]
 	c.fullPath = ""
 	c.index = -1
 	c.data = nil
@@ -64,12 +72,25 @@ func (c *Context) Copy() *Context {
 	cp.Keys = map[string]any{}
 	cp.fullPath = c.fullPath
 	cp.data = nil
+	cParams := c.Params
+	cp.Params = make([]Param, len(cParams))
+	copy(cp.Params, cParams)
 	for k, v := range c.Keys {
 		cp.Keys[k] = v
 	}
 	return &cp
 }
 
+// FullPath returns a matched route full path. For not found routes
+// returns an empty string.
+//
+//	router.GET("/user/:id", func(c *hapi.Context) {
+//	    c.FullPath() == "/user/:id" // true
+//	})
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
 /************************************/
 /*********** FLOW CONTROL ***********/
 /************************************/
@@ -98,6 +119,43 @@ func (c *Context) Abort() {
 	c.index = abortIndex
 }
 
+/************************************/
+/************ INPUT DATA ************/
+/************************************/
+
+// Param returns the value of the URL param.
+// It is a shortcut for c.Params.ByName(key)
+//
+//	router.GET("/user/:id", func(c *hapi.Context) {
+//	    // a GET request to /user/john
+//	    id := c.Param("id") // id == "john"
+//	})
+func (c *Context) Param(key string) string {
+	return c.Params.ByName(key)
+}
+
+// URLFor builds the path of a named route, see Engine.URL.
+func (c *Context) URLFor(name string, params ...interface{}) (string, error) {
+	return c.engine.URL(name, params...)
+}
+
+// Cookie returns the value of the request cookie named name, or http.ErrNoCookie if there is none.
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+// SetCookie adds a Set-Cookie header to the response, the path of the cookie defaults to "/".
+func (c *Context) SetCookie(cookie *http.Cookie) {
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
 func (c *Context) RequestBody() ([]byte, error) {
 	if c.Request.Body == nil {
 		return nil, nil
@@ -131,13 +189,19 @@ func (c *Context) ResponseBodySize() int64 {
 	}
 }
 
+// dataBody is the envelope of the responses written by Context.Data.
+type dataBody struct {
+	XMLName xml.Name    `json:"-" xml:"response"`
+	Code    uint        `json:"code" xml:"code"`
+	Message string      `json:"message" xml:"message"`
+	Data    interface{} `json:"data,omitempty" xml:"data,omitempty"`
+}
+
+// Data writes data, or err, in the envelope {"code", "message", "data"}, encoded by the codec
+// of the media type accepted by the request, see Engine.Codec.
 func (c *Context) Data(data interface{}, err error) {
 	statusCode := http.StatusOK
-	body := struct {
-		Code    uint        `json:"code"`
-		Message string      `json:"message"`
-		Data    interface{} `json:"data,omitempty"`
-	}{}
+	body := dataBody{}
 	if err == nil {
 		body.Code = 0
 		body.Message = `success`
@@ -147,6 +211,11 @@ func (c *Context) Data(data interface{}, err error) {
 			Message() string
 		}); ok && err2.Code() != 0 {
 			body.Code, body.Message = err2.Code(), err2.Message()
+			if err3, ok := err.(interface {
+				StatusCode() int
+			}); ok {
+				statusCode = err3.StatusCode()
+			}
 
 			// if err3, ok := err.(interface {
 			// 	GetError() error
@@ -161,7 +230,7 @@ func (c *Context) Data(data interface{}, err error) {
 	}
 	body.Data = getData(data, err, statusCode)
 
-	c.StatusJson(statusCode, body)
+	c.encode(statusCode, c.responseCodec(), body)
 }
 
 func (c *Context) Ok(message string) {
@@ -179,18 +248,32 @@ func (c *Context) Json(data interface{}) {
 	c.StatusJson(http.StatusOK, data)
 }
 func (c *Context) StatusJson(status int, data interface{}) {
+	c.encode(status, c.engine.codecs[mimeJSON], data)
+}
+
+// encode writes data encoded by codec with status. The data is encoded before anything is written:
+// if codec fails, it is encoded by the JSON codec instead, and if that fails too an error is responded with status 500.
+func (c *Context) encode(status int, codec Codec, data interface{}) {
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, data); err != nil {
+		debugPrint("cannot encode response body: %v", err)
+		buf.Reset()
+		codec = c.engine.codecs[mimeJSON]
+		if err := codec.Encode(&buf, data); err != nil {
+			debugPrint("cannot encode response body: %v", err)
+			buf.Reset()
+			buf.WriteString(`{"code":"json-marshal-error","message":"json marshal error"}`)
+			status = http.StatusInternalServerError
+			codec = jsonCodec{}
+		}
+	}
+
 	// header should be set before WriteHeader or Write
-	c.Writer.Header().Set(`Content-Type`, `application/json; charset=utf-8`)
+	c.Writer.Header().Set(`Content-Type`, codec.ContentType())
 	if v := reflect.ValueOf(c.Writer).Elem().FieldByName(`wroteHeader`); !v.IsValid() || !v.Bool() {
 		c.Writer.WriteHeader(status)
 	}
-
-	encoder := json.NewEncoder(c.Writer)
-	encoder.SetEscapeHTML(false)
-	if err := encoder.Encode(data); err != nil {
-		// c.SetError(err)
-		c.Writer.Write([]byte(`{"code":"json-marshal-error","message":"json marshal error"}`))
-	}
+	c.Writer.Write(buf.Bytes())
 }
 
 func getData(data interface{}, err error, code int) interface{} {
@@ -2,10 +2,14 @@ package hapi
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lovego/struct_tag"
 )
@@ -14,13 +18,22 @@ type todoReqFields struct {
 	Param  bool
 	Query  bool
 	Header bool
+	Cookie bool
 	Body   bool
 	Ctx    bool
 }
 
-func convertHandler(h interface{}, path string) HandlerFunc {
+// convertHandler converts a func(*Context), a TypedHandler or a reflective func(req, resp) handler to a HandlerFunc.
+// The req and resp types of a typed or reflective handler are returned along with it.
+func convertHandler(engine *Engine, h interface{}, host, path string) (HandlerFunc, reflect.Type, reflect.Type) {
 	if handler, ok := h.(func(*Context)); ok {
-		return handler
+		return handler, nil, nil
+	}
+	if handler, ok := h.(HandlerFunc); ok {
+		return handler, nil, nil
+	}
+	if handler, ok := h.(TypedHandler); ok {
+		return handler.convert(engine, host, path)
 	}
 
 	val := reflect.ValueOf(h)
@@ -36,15 +49,13 @@ func convertHandler(h interface{}, path string) HandlerFunc {
 		panic("handler func must have no return values.")
 	}
 
-	reqConvertFunc, hasCtx := newReqConvertFunc(typ.In(0), path)
+	reqConvertFunc, hasCtx := newReqConvertFunc(engine, typ.In(0), host, path)
 	respTyp, respWriteFunc := newRespWriteFunc(typ.In(1), hasCtx)
 
 	return func(ctx *Context) {
 		req, err := reqConvertFunc(ctx)
 		if err != nil {
-			// ctx.Data(nil, errs.New("args-err", err.Error()))
-			// TODO return args err
-			panic("args-err")
+			handleBindingError(ctx, err)
 			return
 		}
 		resp := reflect.New(respTyp)
@@ -52,49 +63,45 @@ func convertHandler(h interface{}, path string) HandlerFunc {
 		if respWriteFunc != nil {
 			respWriteFunc(ctx, resp.Elem())
 		}
+	}, typ.In(0), typ.In(1)
+}
+
+// handleBindingError responds to a request which can't be bound by the BindingErrorHandler, or Context.Data.
+func handleBindingError(ctx *Context, err *BindingError) {
+	if handler := ctx.engine.BindingErrorHandler; handler != nil {
+		handler(ctx, err)
+	} else {
+		ctx.Data(nil, err)
 	}
 }
 
-func newReqConvertFunc(typ reflect.Type, path string) (
-	func(*Context) (reflect.Value, error), bool,
+func newReqConvertFunc(engine *Engine, typ reflect.Type, host, path string) (
+	func(*Context) (reflect.Value, *BindingError), bool,
 ) {
 	isPtr := false
 	if typ.Kind() == reflect.Ptr {
 		isPtr = true
 		typ = typ.Elem()
 	}
-	todo := validateReqFields(typ, path)
+	todo := validateReqFields(typ, host, path)
+	sections := compileReqSections(typ, todo)
+	validation := compileReqValidation(typ, engine.rules)
 
-	return func(ctx *Context) (reflect.Value, error) {
+	return func(ctx *Context) (reflect.Value, *BindingError) {
 		ptr := reflect.New(typ)
 		req := ptr.Elem()
 
-		var err error
-		Traverse(req, func(value reflect.Value, f reflect.StructField) bool {
-			switch f.Name {
-			case "Query":
-				if todo.Query {
-					convertNilPtr(value)
-					err = Query(value, ctx.Request.URL.Query())
-				}
-			case "Header":
-				if todo.Header {
-					convertNilPtr(value)
-					err = Header(value, ctx.Request.Header)
-				}
-			case "Body":
-				if todo.Body {
-					err = convertReqBody(value, ctx)
-				}
-			case "Ctx":
-				if todo.Ctx {
-					value.Set(reflect.ValueOf(ctx))
-				}
+		for i := range sections {
+			section := &sections[i]
+			if err := section.bind(fieldByIndex(req, section.index), ctx); err != nil {
+				// the binding funcs only return a *BindingError
+				return reflect.Value{}, err.(*BindingError)
+			}
+		}
+		if validation != nil {
+			if errs := validation.validate(req, nil); len(errs) > 0 {
+				return reflect.Value{}, &BindingError{Fields: errs}
 			}
-			return err == nil
-		})
-		if err != nil {
-			return reflect.Value{}, err
 		}
 
 		if isPtr {
@@ -107,13 +114,18 @@ func newReqConvertFunc(typ reflect.Type, path string) (
 
 var typeContextPtr = reflect.TypeOf((*Context)(nil))
 
-func validateReqFields(typ reflect.Type, path string) (todo todoReqFields) {
+func validateReqFields(typ reflect.Type, host, path string) (todo todoReqFields) {
 	if typ.Kind() != reflect.Struct {
 		panic("req parameter of handler func must be a struct or struct pointer.")
 	}
 
 	TraverseType(typ, func(f reflect.StructField) {
 		switch f.Name {
+		case "Param":
+			if !isEmptyStruct(f.Type) {
+				ValidateParam(f.Type, host, path)
+				todo.Param = true
+			}
 		case "Query":
 			if !isEmptyStruct(f.Type) {
 				ValidateQuery(f.Type)
@@ -124,6 +136,11 @@ func validateReqFields(typ reflect.Type, path string) (todo todoReqFields) {
 				ValidateHeader(f.Type)
 				todo.Header = true
 			}
+		case "Cookie":
+			if !isEmptyStruct(f.Type) {
+				ValidateCookie(f.Type)
+				todo.Cookie = true
+			}
 		case "Ctx":
 			if f.Type != typeContextPtr {
 				panic("Ctx field of req parameter must be of type '*goa.Context'.")
@@ -140,20 +157,105 @@ func validateReqFields(typ reflect.Type, path string) (todo todoReqFields) {
 	return
 }
 
-func convertReqBody(value reflect.Value, ctx *Context) error {
+// convertReqBody binds the request body according to its Content-Type: form-urlencoded and multipart
+// bodies by form like Form does, other bodies by the codec of their media type, JSON if there is none.
+// form is nil if the body is not a struct, which can't be bound from a form.
+func convertReqBody(value reflect.Value, ctx *Context, form *structBinding) error {
+	mediaType, _, _ := mime.ParseMediaType(ctx.Request.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		if form == nil {
+			return unsupportedMediaType(mediaType)
+		}
+		if err := ctx.Request.ParseForm(); err != nil {
+			return newBindingError("body", "req.Body", err)
+		}
+		return form.bindValues(indirectStruct(value), ctx.Request.PostForm, nil, false, "body", "req.Body.")
+	case "multipart/form-data":
+		if form == nil {
+			return unsupportedMediaType(mediaType)
+		}
+		if err := ctx.Request.ParseMultipartForm(ctx.engine.MaxMultipartMemory); err != nil {
+			return newBindingError("body", "req.Body", err)
+		}
+		multipartForm := ctx.Request.MultipartForm
+		return form.bindValues(indirectStruct(value), multipartForm.Value, multipartForm.File, false, "body", "req.Body.")
+	}
+
 	body, err := ctx.RequestBody()
 	if err != nil {
-		return err
+		return newBindingError("body", "req.Body", err)
 	}
 	if len(body) == 0 {
 		return nil
 	}
-	if err := json.Unmarshal(body, value.Addr().Interface()); err != nil {
-		return fmt.Errorf("req.Body: %s", err.Error())
+	if mediaType == "" {
+		mediaType = mimeJSON
+	}
+	codec := ctx.engine.codec(mediaType)
+	if codec == nil {
+		return unsupportedMediaType(mediaType)
+	}
+	if err := codec.Decode(body, value.Addr().Interface()); err != nil {
+		field := "req.Body"
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			field += "." + goFieldPath(value.Type(), typeErr.Field)
+		}
+		return newBindingError("body", field, err)
 	}
 	return nil
 }
 
+// goFieldPath converts path, the JSON keys to a field of typ joined by dots like json.UnmarshalTypeError.Field,
+// to the names of the Go fields, so that a body field is reported by the same name as for a form.
+// The keys of maps and those not found are kept.
+func goFieldPath(typ reflect.Type, path string) string {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		typ = indirectType(typ)
+		for typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+			typ = indirectType(typ.Elem())
+		}
+		switch typ.Kind() {
+		case reflect.Map:
+			typ = typ.Elem()
+			continue
+		case reflect.Struct:
+			var found *reflect.StructField
+			traverseTypeIndex(typ, nil, func(f reflect.StructField, index []int) {
+				if found == nil && jsonFieldName(f) == key {
+					found = &f
+				}
+			})
+			if found != nil {
+				keys[i], typ = found.Name, found.Type
+				continue
+			}
+		}
+		break
+	}
+	return strings.Join(keys, ".")
+}
+
+// jsonFieldName returns the key of field in JSON, empty if it is skipped.
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func unsupportedMediaType(mediaType string) *BindingError {
+	err := newBindingError("body", "req.Body", errors.New("unsupported Content-Type '"+mediaType+"'"))
+	err.status = http.StatusUnsupportedMediaType
+	return err
+}
+
 func isEmptyStruct(typ reflect.Type) bool {
 	if typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
@@ -165,12 +267,41 @@ func ValidateHeader(typ reflect.Type) {
 	if !isStructOrStructPtr(typ) {
 		panic("req.Header must be struct or pointer to struct.")
 	}
+	bindingOf(typ, "header").validateDefaults("req.Header.")
+}
+
+func ValidateCookie(typ reflect.Type) {
+	if !isStructOrStructPtr(typ) {
+		panic("req.Cookie must be struct or pointer to struct.")
+	}
+	bindingOf(typ, "cookie").validateDefaults("req.Cookie.")
+}
+
+// ValidateParam checks that every field of req.Param refers to a wildcard of host or path.
+func ValidateParam(typ reflect.Type, host, path string) {
+	if !isStructOrStructPtr(typ) {
+		panic("req.Param must be struct or pointer to struct.")
+	}
+	names := append(hostParamNames(host), pathParamNames(path)...)
+	TraverseType(typ, func(f reflect.StructField) {
+		paramName, _ := queryParamName(f)
+		if paramName == "" {
+			return
+		}
+		for _, name := range names {
+			if strings.EqualFold(name, paramName) {
+				return
+			}
+		}
+		panic("req.Param." + f.Name + ": no param named '" + paramName + "' in route '" + host + path + "'")
+	})
 }
 
 func ValidateQuery(typ reflect.Type) {
 	if !isStructOrStructPtr(typ) {
 		panic("req.Query must be struct or pointer to struct.")
 	}
+	bindingOf(typ, "json").validateDefaults("req.Query.")
 }
 
 func isStructOrStructPtr(typ reflect.Type) bool {
@@ -185,29 +316,43 @@ func convertNilPtr(v reflect.Value) {
 	}
 }
 
+// indirectStruct allocates v if it is a nil struct pointer, and returns the struct it points to, or v.
+func indirectStruct(v reflect.Value) reflect.Value {
+	convertNilPtr(v)
+	return reflect.Indirect(v)
+}
+
+// Query sets the fields of value from the query params, a field without param is set to its default tag if any.
+// Params like "filter[status]" or "filter.owner.id" are bound into the nested structs and maps with string keys,
+// and the params of no field into the map field tagged `query:",remain"` or `json:",remain"`, if any.
+// The values of a slice or array field with a split option, like `query:"ids,split=,"`, are split by its separator.
 func Query(value reflect.Value, map2strs map[string][]string) (err error) {
-	if len(map2strs) == 0 {
-		return nil
-	}
-	Traverse(value, func(v reflect.Value, f reflect.StructField) bool {
-		paramName, arrayParamName := queryParamName(f)
-		if paramName == "" {
-			return true
-		}
-		// value is always empty, so Set only when len(values) > 0
-		if values := queryParamValues(map2strs, paramName, arrayParamName); len(values) > 0 {
-			if err = SetArray(v, values); err != nil {
-				err = fmt.Errorf("req.Query.%s: %s", f.Name, err.Error())
-			}
-			return err == nil // if err == nil, go on Traverse
-		}
-		return true // go on Traverse
-	})
-	return
+	return bindingOf(value.Type(), "json").bindValues(
+		reflect.Indirect(value), map2strs, nil, true, "query", "req.Query.",
+	)
 }
 
+var (
+	typeFileHeader      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	typeFileHeaderSlice = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// Form sets the fields of value from the values and files of a form-urlencoded or multipart body.
+// Field names are resolved like Query does, the *multipart.FileHeader and []*multipart.FileHeader
+// fields are set from the uploaded files.
+func Form(value reflect.Value, form *multipart.Form) (err error) {
+	return bindingOf(value.Type(), "json").bindValues(
+		reflect.Indirect(value), form.Value, form.File, false, "body", "req.Body.",
+	)
+}
+
+// queryParamName returns the name of the param of field given by its query tag, or its json tag, or its name.
+// The name followed by "[]" is returned too for a slice or array field.
 func queryParamName(field reflect.StructField) (string, string) {
-	tag := field.Tag.Get("json")
+	tag := field.Tag.Get("query")
+	if tag == "" || tag[0] == ',' {
+		tag = field.Tag.Get("json")
+	}
 	if tag == "-" {
 		return "", ""
 	}
@@ -225,42 +370,34 @@ func queryParamName(field reflect.StructField) (string, string) {
 	return name, ""
 }
 
-func queryParamValues(map2strs map[string][]string, paramName, arrayParamName string) []string {
-	if values, ok := map2strs[paramName]; ok {
-		return values
-	}
-	if arrayParamName != "" {
-		if values, ok := map2strs[arrayParamName]; ok {
-			return values
-		}
-	}
+// PathParams sets the fields of value from the URL params matched by the router.
+// Field names are resolved like Query does.
+func PathParams(value reflect.Value, params Params) (err error) {
+	return bindingOf(value.Type(), "json").bindParams(reflect.Indirect(value), params)
+}
 
-	paramName, arrayParamName = strings.ToLower(paramName), strings.ToLower(arrayParamName)
-	for key, values := range map2strs {
-		key = strings.ToLower(key)
-		if key == paramName || arrayParamName != "" && key == arrayParamName {
-			return values
+func pathParamValue(params Params, paramName string) string {
+	if value, ok := params.Get(paramName); ok {
+		return value
+	}
+	for _, param := range params {
+		if strings.EqualFold(param.Key, paramName) {
+			return param.Value
 		}
 	}
-
-	return nil
+	return ""
 }
 
+// Header sets the fields of value from the request headers, a field without header is set to its default tag if any.
+// A slice or array field gets all the values of its header, split by its split option like `header:"Accept-Encoding,split=,"`.
 func Header(value reflect.Value, map2strs map[string][]string) (err error) {
-	Traverse(value, func(v reflect.Value, f reflect.StructField) bool {
-		key, _ := struct_tag.Lookup(string(f.Tag), "header")
-		if key == "" {
-			key = f.Name
-		}
-		values := map2strs[key]
-		if len(values) > 0 && values[0] != "" {
-			if err = Set(v, values[0]); err != nil {
-				err = fmt.Errorf("req.Header.%s: %s", f.Name, err.Error())
-			}
-		}
-		return err == nil // if err == nil, go on Traverse
-	})
-	return
+	return bindingOf(value.Type(), "header").bindHeader(reflect.Indirect(value), map2strs)
+}
+
+// Cookies sets the fields of value from the request cookies named by their cookie tag, or their name.
+// A field without cookie is set to its default tag if any.
+func Cookies(value reflect.Value, cookies []*http.Cookie) (err error) {
+	return bindingOf(value.Type(), "cookie").bindCookies(reflect.Indirect(value), cookies)
 }
 
 func newRespWriteFunc(typ reflect.Type, hasCtx bool) (reflect.Type, func(*Context, reflect.Value)) {
@@ -274,6 +411,7 @@ func newRespWriteFunc(typ reflect.Type, hasCtx bool) (reflect.Type, func(*Contex
 	if validateRespFields(typ) {
 		return typ, nil
 	}
+	fields := compileRespFields(typ)
 	return typ, func(ctx *Context, resp reflect.Value) {
 		if hasCtx && ctx.ResponseBodySize() > 0 {
 			return
@@ -282,8 +420,10 @@ func newRespWriteFunc(typ reflect.Type, hasCtx bool) (reflect.Type, func(*Contex
 		var data interface{}
 		var err error
 
-		Traverse(resp, func(v reflect.Value, f reflect.StructField) bool {
-			switch f.Name {
+		for i := range fields {
+			f := &fields[i]
+			v := fieldByIndex(resp, f.index)
+			switch f.name {
 			case "Error":
 				if e := v.Interface(); e != nil {
 					err = e.(error)
@@ -291,10 +431,15 @@ func newRespWriteFunc(typ reflect.Type, hasCtx bool) (reflect.Type, func(*Contex
 			case "Data":
 				data = v.Interface()
 			case "Header":
-				WriteRespHeader(v, ctx.Writer.Header())
+				if v = reflect.Indirect(v); v.IsValid() {
+					f.binding.writeHeader(v, ctx.Writer.Header())
+				}
+			case "Cookie":
+				if v = reflect.Indirect(v); v.IsValid() {
+					f.binding.writeCookies(v, ctx.Writer)
+				}
 			}
-			return true
-		})
+		}
 		ctx.Data(data, err)
 	}
 }
@@ -313,6 +458,8 @@ func validateRespFields(typ reflect.Type) bool {
 			}
 		case "Header":
 			ValidateRespHeader(f.Type)
+		case "Cookie":
+			ValidateRespCookie(f.Type)
 		default:
 			panic("Unknown field: resp." + f.Name)
 		}
@@ -336,16 +483,93 @@ func ValidateRespHeader(typ reflect.Type) {
 	})
 	return
 }
+
+// WriteRespHeader sets the headers of the non-empty fields of value, a resp.Header.
 func WriteRespHeader(value reflect.Value, header http.Header) {
-	Traverse(value, func(v reflect.Value, f reflect.StructField) bool {
-		if value := v.String(); value != "" {
-			key, _ := struct_tag.Lookup(string(f.Tag), "header")
-			if key == "" {
-				key = f.Name
+	bindingOf(value.Type(), "header").writeHeader(reflect.Indirect(value), header)
+}
+
+var typeCookiePtr = reflect.TypeOf((*http.Cookie)(nil))
+
+// ValidateRespCookie checks that the fields of resp.Cookie are strings with a valid cookie tag, or *http.Cookie.
+func ValidateRespCookie(typ reflect.Type) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		panic("resp.Cookie must be struct or pointer to struct.")
+	}
+	TraverseType(typ, func(f reflect.StructField) {
+		switch {
+		case f.Type == typeCookiePtr:
+		case f.Type.Kind() == reflect.String:
+			if _, _, err := respCookie(f); err != nil {
+				panic("resp.Cookie." + f.Name + ": " + err.Error())
 			}
-			header.Set(key, value)
+		default:
+			panic("resp.Cookie." + f.Name + ": type must be string or *http.Cookie.")
 		}
-		return false
 	})
-	return
+}
+
+// WriteRespCookie sets the cookies of the fields of value, a resp.Cookie, skipping the empty ones.
+// A string field is the value of a cookie whose name and attributes are given by its cookie tag:
+//
+//	Session string `cookie:"session,path=/,maxage=3600,secure,httponly,samesite=lax"`
+//
+// The expiry is set by maxage in seconds, or by expires, a duration like "24h" after which the cookie
+// expires from the time it is written, for the clients not supporting Max-Age.
+func WriteRespCookie(value reflect.Value, w http.ResponseWriter) {
+	bindingOf(value.Type(), "cookie").writeCookies(reflect.Indirect(value), w)
+}
+
+// respCookie returns the cookie described by the cookie tag of a resp.Cookie field, without its value,
+// and the duration of its expires attribute, 0 if there is none.
+func respCookie(f reflect.StructField) (cookie *http.Cookie, expires time.Duration, err error) {
+	tag, _ := struct_tag.Lookup(string(f.Tag), "cookie")
+	parts := strings.Split(tag, ",")
+	cookie = &http.Cookie{Name: parts[0], Path: "/"}
+	if cookie.Name == "" {
+		cookie.Name = f.Name
+	}
+	for _, attr := range parts[1:] {
+		key, value := attr, ""
+		if i := strings.IndexByte(attr, '='); i >= 0 {
+			key, value = attr[:i], attr[i+1:]
+		}
+		switch strings.ToLower(key) {
+		case "path":
+			cookie.Path = value
+		case "domain":
+			cookie.Domain = value
+		case "maxage":
+			maxAge, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, 0, errors.New("invalid maxage '" + value + "'")
+			}
+			cookie.MaxAge = maxAge
+		case "expires":
+			if expires, err = time.ParseDuration(value); err != nil || expires <= 0 {
+				return nil, 0, errors.New("invalid expires '" + value + "', must be a positive duration")
+			}
+		case "secure":
+			cookie.Secure = true
+		case "httponly":
+			cookie.HttpOnly = true
+		case "samesite":
+			switch strings.ToLower(value) {
+			case "lax":
+				cookie.SameSite = http.SameSiteLaxMode
+			case "strict":
+				cookie.SameSite = http.SameSiteStrictMode
+			case "none":
+				cookie.SameSite = http.SameSiteNoneMode
+			default:
+				return nil, 0, errors.New("invalid samesite '" + value + "'")
+			}
+		default:
+			return nil, 0, errors.New("unknown cookie attribute '" + key + "'")
+		}
+	}
+	return cookie, expires, nil
 }
@@ -0,0 +1,639 @@
+package hapi
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBindingError(t *testing.T) {
+	router := New()
+	called := false
+	router.POST("/users/:id", func(req *struct {
+		Param struct {
+			Id int `json:"id"`
+		}
+		Query struct {
+			Page uint8 `json:"page"`
+		}
+		Header struct {
+			Limit int `header:"X-Limit"`
+		}
+		Body struct {
+			Name string `json:"name"`
+			Age  int    `json:"age"`
+		}
+	}, resp *struct{}) {
+		called = true
+	})
+
+	tests := []struct {
+		path   string
+		header string
+		body   string
+		resp   string
+	}{
+		{"/users/abc", "", "", `{"code":1001,"message":"req.Param.Id: invalid value \"abc\"",` +
+			`"data":[{"field":"req.Param.Id","location":"param","reason":"invalid value \"abc\""}]}`},
+		{"/users/1?page=300", "", "", `{"code":1001,"message":"req.Query.Page: value \"300\" out of range",` +
+			`"data":[{"field":"req.Query.Page","location":"query","reason":"value \"300\" out of range"}]}`},
+		{"/users/1", "x", "", `{"code":1001,"message":"req.Header.Limit: invalid value \"x\"",` +
+			`"data":[{"field":"req.Header.Limit","location":"header","reason":"invalid value \"x\""}]}`},
+		{"/users/1", "", `{"age":"x"}`, `{"code":1001,"message":"req.Body.Age: invalid value: expected int, got string",` +
+			`"data":[{"field":"req.Body.Age","location":"body","reason":"invalid value: expected int, got string"}]}`},
+		{"/users/1", "", `{"name":`, `{"code":1001,"message":"req.Body: unexpected end of JSON input",` +
+			`"data":[{"field":"req.Body","location":"body","reason":"unexpected end of JSON input"}]}`},
+	}
+	for _, test := range tests {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+		if test.header != "" {
+			req.Header.Set("X-Limit", test.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code %d", test.path, w.Code)
+		}
+		if body := w.Body.String(); body != test.resp+"\n" {
+			t.Errorf("%s: body %s", test.path, body)
+		}
+		if called {
+			t.Errorf("%s: handler called", test.path)
+		}
+	}
+
+	router.BindingErrorHandler = func(c *Context, err *BindingError) {
+		c.StatusJson(http.StatusUnprocessableEntity, map[string]string{"field": err.Fields[0].Field})
+	}
+	w := performRequest(router, http.MethodPost, "/users/abc")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if body := w.Body.String(); body != `{"field":"req.Param.Id"}`+"\n" {
+		t.Errorf("body: %s", body)
+	}
+}
+
+func TestBodyBindingErrorField(t *testing.T) {
+	router := New()
+	router.POST("/users", func(req *struct {
+		Body struct {
+			Age     int `json:"age"`
+			Address struct {
+				Zip int `json:"zip_code"`
+			} `json:"address"`
+		}
+	}, resp *struct{}) {
+	})
+
+	tests := []struct {
+		contentType, body, field string
+	}{
+		{"application/json", `{"age":"x"}`, "req.Body.Age"},
+		{"application/x-www-form-urlencoded", "age=x", "req.Body.Age"},
+		{"application/json", `{"address":{"zip_code":"x"}}`, "req.Body.Address.Zip"},
+		{"application/x-www-form-urlencoded", "address[zip_code]=x", "req.Body.Address.Zip"},
+	}
+	for _, test := range tests {
+		var got *BindingError
+		router.BindingErrorHandler = func(c *Context, err *BindingError) {
+			got = err
+		}
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		if got == nil || got.Fields[0].Field != test.field {
+			t.Errorf("%s %s: error %+v", test.contentType, test.body, got)
+		}
+	}
+}
+
+func TestQueryBindingKeyCase(t *testing.T) {
+	router := New()
+	var got struct {
+		Page int
+		Ids  []int
+	}
+	router.GET("/users", func(req *struct {
+		Query struct {
+			Page int   `json:"page" default:"1"`
+			Ids  []int `json:"ids"`
+		}
+	}, resp *struct{}) {
+		got.Page, got.Ids = req.Query.Page, req.Query.Ids
+	})
+
+	tests := []struct {
+		query string
+		page  int
+		ids   []int
+	}{
+		{"", 1, nil},
+		{"PAGE=2&IDS[]=3&IDS[]=4", 2, []int{3, 4}},
+		{"Page=2&page=3&Ids=5&ids[]=6", 3, []int{6}},
+		{"ids=7&other=8", 1, []int{7}},
+	}
+	for _, test := range tests {
+		got.Page, got.Ids = 0, nil
+		if w := performRequest(router, http.MethodGet, "/users?"+test.query); w.Code != http.StatusOK {
+			t.Errorf("%s: status code %d", test.query, w.Code)
+		}
+		if got.Page != test.page || !reflect.DeepEqual(got.Ids, test.ids) {
+			t.Errorf("%s: bound %+v", test.query, got)
+		}
+	}
+}
+
+func TestFormBodyBinding(t *testing.T) {
+	router := New()
+	var got struct {
+		Name  string
+		Ids   []int
+		Age   int
+		Files []string
+	}
+	router.POST("/users", func(req *struct {
+		Body *struct {
+			Name   string                  `json:"name"`
+			Ids    []int                   `json:"ids"`
+			Age    int                     `json:"age"`
+			Avatar *multipart.FileHeader   `json:"avatar"`
+			Docs   []*multipart.FileHeader `json:"docs"`
+		}
+	}, resp *struct{}) {
+		got.Name, got.Ids, got.Age, got.Files = req.Body.Name, req.Body.Ids, req.Body.Age, nil
+		if req.Body.Avatar != nil {
+			got.Files = append(got.Files, req.Body.Avatar.Filename)
+		}
+		for _, doc := range req.Body.Docs {
+			got.Files = append(got.Files, doc.Filename)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users?name=query", strings.NewReader("name=hapi&ids=1&ids[]=2&Age=3"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("form: status code %d", w.Code)
+	}
+	if got.Name != "hapi" || !reflect.DeepEqual(got.Ids, []int{1}) || got.Age != 3 {
+		t.Errorf("form: bound %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("age=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if body := w.Body.String(); w.Code != http.StatusBadRequest || !strings.Contains(body, `"field":"req.Body.Age","location":"body"`) {
+		t.Errorf("form: status code %d, body %s", w.Code, body)
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "multi")
+	mw.WriteField("ids[]", "4")
+	mw.WriteField("ids[]", "5")
+	for _, file := range []struct{ field, name string }{{"avatar", "a.png"}, {"docs", "1.txt"}, {"docs", "2.txt"}} {
+		fw, _ := mw.CreateFormFile(file.field, file.name)
+		fw.Write([]byte(strings.Repeat("x", 1024)))
+	}
+	mw.Close()
+
+	router.MaxMultipartMemory = 1024
+	req = httptest.NewRequest(http.MethodPost, "/users", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("multipart: status code %d", w.Code)
+	}
+	if got.Name != "multi" || !reflect.DeepEqual(got.Ids, []int{4, 5}) ||
+		!reflect.DeepEqual(got.Files, []string{"a.png", "1.txt", "2.txt"}) {
+		t.Errorf("multipart: bound %+v", got)
+	}
+	if req.MultipartForm != nil {
+		req.MultipartForm.RemoveAll()
+	}
+}
+
+func TestDefaultTag(t *testing.T) {
+	router := New()
+	type query struct {
+		Page  int      `json:"page" default:"1"`
+		Size  *int     `json:"size" default:"20"`
+		Sort  string   `json:"sort" default:"id"`
+		Tags  []string `json:"tags" default:"all"`
+		Since string   `json:"since"`
+	}
+	var got query
+	var lang string
+	router.GET("/users", func(req *struct {
+		Query  query
+		Header struct {
+			Lang string `header:"Accept-Language" default:"en"`
+		}
+	}, resp *struct{}) {
+		got, lang = req.Query, req.Header.Lang
+	})
+
+	performRequest(router, http.MethodGet, "/users")
+	if got.Page != 1 || got.Size == nil || *got.Size != 20 || got.Sort != "id" ||
+		!reflect.DeepEqual(got.Tags, []string{"all"}) || got.Since != "" || lang != "en" {
+		t.Errorf("defaults: %+v, lang %q", got, lang)
+	}
+
+	performRequest(router, http.MethodGet, "/users?page=3&size=0&tags=a&tags=b", header{"Accept-Language", "fr"})
+	if got.Page != 3 || got.Size == nil || *got.Size != 0 || !reflect.DeepEqual(got.Tags, []string{"a", "b"}) || lang != "fr" {
+		t.Errorf("values: %+v, lang %q", got, lang)
+	}
+
+	if recv := catchPanic(func() {
+		router.GET("/bad", func(req *struct {
+			Query struct {
+				Page int `default:"x"`
+			}
+		}, resp *struct{}) {
+		})
+	}); recv == nil {
+		t.Error("no panic for invalid query default")
+	}
+	if recv := catchPanic(func() {
+		router.GET("/bad", func(req *struct {
+			Header struct {
+				Limit uint8 `default:"300"`
+			}
+		}, resp *struct{}) {
+		})
+	}); recv == nil {
+		t.Error("no panic for invalid header default")
+	}
+}
+
+func TestCookie(t *testing.T) {
+	router := New()
+	var session string
+	var theme string
+	router.GET("/", func(req *struct {
+		Cookie struct {
+			Session string `cookie:"session" validate:"required"`
+			Theme   string `cookie:"theme" default:"light"`
+			Visits  int    `cookie:"visits"`
+		}
+	}, resp *struct {
+		Data   interface{}
+		Cookie struct {
+			Session string       `cookie:"session,maxage=3600,secure,httponly,samesite=lax"`
+			Theme   string       `cookie:"theme,path=/app"`
+			Empty   string       `cookie:"empty"`
+			Raw     *http.Cookie `cookie:"raw"`
+			Login   string       `cookie:"login,expires=24h"`
+		}
+	}) {
+		session, theme = req.Cookie.Session, req.Cookie.Theme
+		resp.Cookie.Session = "renewed"
+		resp.Cookie.Theme = req.Cookie.Theme
+		resp.Cookie.Raw = &http.Cookie{Name: "lang", Value: "en", MaxAge: -1}
+		resp.Cookie.Login = "yes"
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || session != "s1" || theme != "light" {
+		t.Errorf("status code %d, session %q, theme %q", w.Code, session, theme)
+	}
+	expected := []string{
+		"session=renewed; Path=/; Max-Age=3600; HttpOnly; Secure; SameSite=Lax",
+		"theme=light; Path=/app",
+		"lang=en; Max-Age=0",
+	}
+	cookies := w.Header().Values("Set-Cookie")
+	if len(cookies) != 4 || !reflect.DeepEqual(cookies[:3], expected) {
+		t.Fatalf("Set-Cookie: %q", cookies)
+	}
+	if login := (&http.Response{Header: http.Header{"Set-Cookie": cookies[3:]}}).Cookies(); len(login) != 1 ||
+		login[0].Value != "yes" || time.Until(login[0].Expires) < 23*time.Hour || time.Until(login[0].Expires) > 24*time.Hour {
+		t.Errorf("expires: %q", cookies[3])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "visits", Value: "x"})
+	var got *BindingError
+	router.BindingErrorHandler = func(c *Context, err *BindingError) { got = err }
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if got == nil || got.Fields[0].Field != "req.Cookie.Visits" || got.Fields[0].Location != "cookie" {
+		t.Errorf("binding error: %v", got)
+	}
+
+	if recv := catchPanic(func() {
+		router.GET("/bad", func(req *struct{}, resp *struct {
+			Cookie struct {
+				Session string `cookie:"session,samesite=loose"`
+			}
+		}) {
+		})
+	}); recv == nil {
+		t.Error("no panic for invalid cookie tag")
+	}
+	if recv := catchPanic(func() {
+		router.GET("/bad", func(req *struct{}, resp *struct {
+			Cookie struct {
+				Session string `cookie:"session,expires=2030-01-01"`
+			}
+		}) {
+		})
+	}); recv == nil {
+		t.Error("no panic for invalid expires")
+	}
+}
+
+func TestContextCookie(t *testing.T) {
+	router := New()
+	router.GET("/", func(c *Context) {
+		value, err := c.Cookie("session")
+		if err != nil || value != "s1" {
+			t.Errorf("cookie: %q, %v", value, err)
+		}
+		if _, err := c.Cookie("none"); err != http.ErrNoCookie {
+			t.Errorf("missing cookie: %v", err)
+		}
+		c.SetCookie(&http.Cookie{Name: "session", Value: "s2", HttpOnly: true})
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if cookie := w.Header().Get("Set-Cookie"); cookie != "session=s2; Path=/; HttpOnly" {
+		t.Errorf("Set-Cookie: %q", cookie)
+	}
+}
+
+func TestNestedQuery(t *testing.T) {
+	router := New()
+	type owner struct {
+		Id   int    `json:"id"`
+		Name string `json:"name" default:"me"`
+	}
+	type query struct {
+		Filter *struct {
+			Status string   `json:"status"`
+			Tags   []string `json:"tags"`
+			Owner  owner    `json:"owner"`
+		} `json:"filter"`
+		Labels map[string]string   `json:"labels"`
+		Range  map[string][]int    `json:"range"`
+		Page   int                 `json:"page"`
+		Extra  map[string][]string `json:",remain"`
+	}
+	var got query
+	router.GET("/issues", func(req *struct{ Query query }, resp *struct{}) {
+		got = req.Query
+	})
+
+	w := performRequest(router, http.MethodGet, "/issues?filter[status]=open&filter[owner.id]=3&Filter.Tags[]=a&"+
+		"filter[tags][]=b&labels[team]=web&labels.env=prod&range[id]=1&range[id]=9&page=2&utm_source=mail&x[y]=z")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code %d, body %s", w.Code, w.Body.String())
+	}
+	if got.Filter == nil || got.Filter.Status != "open" || got.Filter.Owner != (owner{3, "me"}) || len(got.Filter.Tags) != 1 {
+		t.Errorf("filter: %+v", got.Filter)
+	}
+	if !reflect.DeepEqual(got.Labels, map[string]string{"team": "web", "env": "prod"}) ||
+		!reflect.DeepEqual(got.Range, map[string][]int{"id": {1, 9}}) || got.Page != 2 ||
+		!reflect.DeepEqual(got.Extra, map[string][]string{"utm_source": {"mail"}, "x[y]": {"z"}}) {
+		t.Errorf("query: %+v", got)
+	}
+
+	w = performRequest(router, http.MethodGet, "/issues?filter[owner][id]=x")
+	if body := w.Body.String(); w.Code != http.StatusBadRequest || !strings.Contains(body, `"field":"req.Query.Filter.Owner.Id"`) {
+		t.Errorf("nested error: status code %d, body %s", w.Code, body)
+	}
+	w = performRequest(router, http.MethodGet, "/issues?range[id]=x")
+	if body := w.Body.String(); w.Code != http.StatusBadRequest || !strings.Contains(body, `"field":"req.Query.Range[id]"`) {
+		t.Errorf("map error: status code %d, body %s", w.Code, body)
+	}
+
+	router.GET("/remain", func(req *struct {
+		Query struct {
+			Ids   []int               `query:"ids,split=,"`
+			Extra map[string][]string `query:",remain"`
+		}
+	}, resp *struct{}) {
+		got.Extra = req.Query.Extra
+	})
+	if w := performRequest(router, http.MethodGet, "/remain?ids=1,2&utm_source=mail"); w.Code != http.StatusOK ||
+		!reflect.DeepEqual(got.Extra, map[string][]string{"utm_source": {"mail"}}) {
+		t.Errorf("query remain: status code %d, extra %v", w.Code, got.Extra)
+	}
+
+	if recv := catchPanic(func() {
+		router.GET("/bad", func(req *struct {
+			Query struct {
+				Extra string `json:",remain"`
+			}
+		}, resp *struct{}) {
+		})
+	}); recv == nil {
+		t.Error("no panic for a string remain field")
+	}
+	if recv := catchPanic(func() {
+		router.GET("/bad", func(req *struct {
+			Query struct {
+				Filter struct {
+					Page int `default:"x"`
+				}
+			}
+		}, resp *struct{}) {
+		})
+	}); recv == nil {
+		t.Error("no panic for an invalid nested default")
+	}
+}
+
+func TestSplitOption(t *testing.T) {
+	router := New()
+	type query struct {
+		Ids    []int    `query:"ids,split=,"`
+		Tags   []string `json:"tags" query:",split=|" default:"a|b"`
+		Filter struct {
+			States []string `json:"states" query:",split=,"`
+		} `json:"filter"`
+	}
+	var got query
+	var encodings, langs []string
+	router.GET("/", func(req *struct {
+		Query  query
+		Header struct {
+			Encodings []string `header:"Accept-Encoding,split=,"`
+			Langs     []string `header:"Accept-Language"`
+		}
+	}, resp *struct{}) {
+		got, encodings, langs = req.Query, req.Header.Encodings, req.Header.Langs
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?ids=1,2,&ids=3&filter[states]=open,closed", nil)
+	req.Header.Add("Accept-Encoding", "gzip, deflate")
+	req.Header.Add("Accept-Encoding", "br")
+	req.Header.Add("Accept-Language", "en")
+	req.Header.Add("Accept-Language", "fr")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code %d, body %s", w.Code, w.Body.String())
+	}
+	if !reflect.DeepEqual(got.Ids, []int{1, 2, 3}) || !reflect.DeepEqual(got.Tags, []string{"a", "b"}) ||
+		!reflect.DeepEqual(got.Filter.States, []string{"open", "closed"}) {
+		t.Errorf("query: %+v", got)
+	}
+	if !reflect.DeepEqual(encodings, []string{"gzip", "deflate", "br"}) || !reflect.DeepEqual(langs, []string{"en", "fr"}) {
+		t.Errorf("header: %q, %q", encodings, langs)
+	}
+
+	w = performRequest(router, http.MethodGet, "/?ids=1,x")
+	if body := w.Body.String(); w.Code != http.StatusBadRequest || !strings.Contains(body, `"field":"req.Query.Ids"`) {
+		t.Errorf("status code %d, body %s", w.Code, body)
+	}
+
+	for name, register := range map[string]func(){
+		"split of a scalar": func() {
+			router.GET("/a", func(req *struct {
+				Query struct {
+					Id int `query:"id,split=,"`
+				}
+			}, resp *struct{}) {
+			})
+		},
+		"empty separator": func() {
+			router.GET("/b", func(req *struct {
+				Header struct {
+					Ids []int `header:"X-Ids,split="`
+				}
+			}, resp *struct{}) {
+			})
+		},
+	} {
+		if recv := catchPanic(register); recv == nil {
+			t.Errorf("no panic for %s", name)
+		}
+	}
+}
+
+type Paging struct {
+	Page int `json:"page" default:"1"`
+}
+
+func TestEmbeddedFieldsAndRespHeader(t *testing.T) {
+	router := New()
+	var page int
+	router.GET("/users", func(req *struct {
+		Query struct {
+			*Paging
+			Sort string `json:"sort"`
+		}
+	}, resp *struct {
+		Header struct {
+			Total string `header:"X-Total"`
+			Page  string `header:"X-Page"`
+		}
+	}) {
+		page = req.Query.Page
+		resp.Header.Total = "100"
+		resp.Header.Page = strconv.Itoa(page)
+	})
+	w := performRequest(router, http.MethodGet, "/users?sort=id")
+	if page != 1 || w.Header().Get("X-Total") != "100" || w.Header().Get("X-Page") != "1" {
+		t.Errorf("page %d, headers %v", page, w.Header())
+	}
+
+	router.POST("/tags", func(req *struct{ Body []string }, resp *struct{}) {})
+	req := httptest.NewRequest(http.MethodPost, "/tags", strings.NewReader("tags=a"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("form into a slice body: status code %d", w.Code)
+	}
+}
+
+// The binding benchmarks only use newReqConvertFunc, so they also run on the tree before the bindings
+// were compiled once per type: copy them to a test file of the commit before "Compile req and resp
+// bindings once per type" and run go test -bench Bind there to get the reference numbers, e.g. on
+// the same machine, in ns/op and allocs/op:
+//
+//	                              before       after
+//	BenchmarkBindQuery             6500, 12    3000, 11
+//	BenchmarkBindQueryAndJSONBody  9000, 14    5900, 13
+//	BenchmarkBindQueryAndFormBody  8400, 16    4200, 14
+type benchQuery struct {
+	Page   int      `json:"page" default:"1"`
+	Size   int      `json:"size" default:"20"`
+	Sort   string   `json:"sort"`
+	Tags   []string `json:"tags"`
+	Status *string  `json:"status"`
+	Since  string   `json:"since"`
+}
+
+type benchBody struct {
+	Name    string   `json:"name"`
+	Email   string   `json:"email"`
+	Age     int      `json:"age"`
+	Roles   []string `json:"roles"`
+	Enabled bool     `json:"enabled"`
+}
+
+func benchmarkBinding(b *testing.B, typ reflect.Type, contentType, body string) {
+	engine := New()
+	convert, _ := newReqConvertFunc(engine, typ, "", "/users/:id")
+	req := httptest.NewRequest(http.MethodPost, "/users/1?page=2&sort=name&tags=a&tags=b&status=open", nil)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Request-Id", "abc")
+	ctx := &Context{engine: engine, Request: req, Params: Params{{Key: "id", Value: "1"}}}
+	if contentType == mimeJSON {
+		ctx.data = map[string]interface{}{ReqBodyKey: []byte(body)}
+	} else {
+		req.Body = io.NopCloser(strings.NewReader(body)) // parsed once into req.PostForm
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := convert(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBindQuery(b *testing.B) {
+	benchmarkBinding(b, reflect.TypeOf(struct {
+		Param struct {
+			Id int `json:"id"`
+		}
+		Query  benchQuery
+		Header struct {
+			RequestId string `header:"X-Request-Id"`
+		}
+	}{}), "", "")
+}
+
+func BenchmarkBindQueryAndJSONBody(b *testing.B) {
+	benchmarkBinding(b, reflect.TypeOf(struct {
+		Query benchQuery
+		Body  benchBody
+	}{}), mimeJSON, `{"name":"hapi","email":"a@b.c","age":3,"roles":["admin"],"enabled":true}`)
+}
+
+func BenchmarkBindQueryAndFormBody(b *testing.B) {
+	benchmarkBinding(b, reflect.TypeOf(struct {
+		Query benchQuery
+		Body  benchBody
+	}{}), "application/x-www-form-urlencoded", "name=hapi&email=a%40b.c&age=3&roles=admin&enabled=true")
+}
@@ -1,5 +1,14 @@
 package hapi
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
 // // MarshalJSON implements the json.Marshaller interface.
 // func (msg *Error) MarshalJSON() ([]byte, error) {
 // 	return json.Marshal(msg.JSON())
@@ -9,3 +18,65 @@ package hapi
 // func (msg *Error) Unwrap() error {
 // 	return msg.Err
 // }
+
+// FieldError describes a request value which can't be bound to a field of the req parameter of a handler.
+type FieldError struct {
+	Field    string `json:"field" xml:"field"`       // the field of req, e.g. "req.Query.Id"
+	Location string `json:"location" xml:"location"` // where the value comes from: "param", "query", "header", "cookie" or "body"
+	Reason   string `json:"reason" xml:"reason"`
+}
+
+// BindingError is the error of a request which can't be bound to the req parameter of a handler.
+// Context.Data responds to it with status 400, or 415 for an unsupported body Content-Type,
+// code ArgsErr and the field errors as data.
+type BindingError struct {
+	Fields []FieldError
+	status int
+}
+
+func newBindingError(location, field string, err error) *BindingError {
+	return &BindingError{Fields: []FieldError{{Field: field, Location: location, Reason: bindingReason(err)}}}
+}
+
+func (e *BindingError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		reasons[i] = f.Field + ": " + f.Reason
+	}
+	return strings.Join(reasons, "; ")
+}
+
+func (e *BindingError) Code() uint {
+	return ArgsErr
+}
+
+func (e *BindingError) Message() string {
+	return e.Error()
+}
+
+func (e *BindingError) Data() interface{} {
+	return e.Fields
+}
+
+func (e *BindingError) StatusCode() int {
+	if e.status != 0 {
+		return e.status
+	}
+	return http.StatusBadRequest
+}
+
+// bindingReason describes err without the details of the strconv functions.
+func bindingReason(err error) string {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		if numErr.Err == strconv.ErrRange {
+			return fmt.Sprintf("value %q out of range", numErr.Num)
+		}
+		return fmt.Sprintf("invalid value %q", numErr.Num)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("invalid value: expected %s, got %s", typeErr.Type, typeErr.Value)
+	}
+	return err.Error()
+}
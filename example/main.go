@@ -26,6 +26,11 @@ func main() {
 	}) {
 		resp.Data = (*IndexResp)(&req.Query)
 	})
+	serv.GET("/typed", hapi.Handle(func(ctx *hapi.Context, req *struct {
+		Query IndexReq
+	}) (*IndexResp, error) {
+		return (*IndexResp)(&req.Query), nil
+	}))
 	serv.GET("/err", func(req *struct {
 		Query IndexReq
 	}, resp *struct {
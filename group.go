@@ -2,11 +2,30 @@ package hapi
 
 import (
 	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	// anyMethods for RouterGroup Any method
+	anyMethods = []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+		http.MethodTrace,
+	}
+
+	// regEnLetter matches english letters for http method name
+	regEnLetter = regexp.MustCompile("^[A-Z]+$")
 )
 
 type Group interface {
 	Group(string, ...HandlerFunc) *RouterGroup
 
+	Handle(string, string, interface{}) Group
+	Any(string, interface{}) Group
+	Match([]string, string, interface{}) Group
+	Mount(string, http.Handler) Group
+
 	GET(string, interface{}) Group
 	POST(string, interface{}) Group
 	DELETE(string, interface{}) Group
@@ -14,6 +33,9 @@ type Group interface {
 	PUT(string, interface{}) Group
 	OPTIONS(string, interface{}) Group
 	HEAD(string, interface{}) Group
+
+	Name(string) Group
+	Remove(string, string) bool
 }
 
 // RouterGroup is used internally to configure router, a RouterGroup is associated with
@@ -23,6 +45,11 @@ type RouterGroup struct {
 	basePath string
 	engine   *Engine
 	root     bool
+	host     string
+	version  string
+	lastPath string
+	// lastRoutes are the methods and paths of the routes added by the last handle, Any, Match or Mount call.
+	lastRoutes []RouteInfo
 }
 
 // var _ Group = &RouterGroup{}
@@ -33,12 +60,41 @@ func (group *RouterGroup) BasePath() string {
 }
 
 func (group *RouterGroup) handle(httpMethod, relativePath string, handler interface{}) Group {
-	absolutePath := group.calculateAbsolutePath(relativePath)
-	handlers := group.combineHandlers(convertHandler(handler, relativePath))
-	group.engine.addRoute(httpMethod, absolutePath, handlers)
+	group.lastRoutes = nil
+	group.handleRoute(httpMethod, relativePath, handler)
 	return group.returnObj()
 }
 
+// handleRoute registers a route without resetting the routes added by the current call, see RouterGroup.Name.
+func (group *RouterGroup) handleRoute(httpMethod, relativePath string, handler interface{}) {
+	absolutePath := group.calculateAbsolutePath(relativePath)
+	handlerFunc, reqTyp, respTyp := convertHandler(group.engine, handler, group.host, absolutePath)
+	group.addRoute(httpMethod, absolutePath, handlerFunc, RouteInfo{
+		Handler: handlerName(handler),
+		Req:     reqTyp,
+		Resp:    respTyp,
+	})
+}
+
+// handlerName returns the name of the func of handler, the one passed to Handle for a TypedHandler.
+func handlerName(handler interface{}) string {
+	if typed, ok := handler.(TypedHandler); ok {
+		handler = typed.handler
+	}
+	return nameOfFunction(handler)
+}
+
+// addRoute registers handlerFunc after the group middleware, info describes the handler for Engine.Routes.
+func (group *RouterGroup) addRoute(httpMethod, absolutePath string, handlerFunc HandlerFunc, info RouteInfo) {
+	info.Method, info.Host, info.Path, info.Version = httpMethod, group.host, absolutePath, group.version
+	info.Middlewares = namesOfFunctions(group.Handlers)
+	info.HandlerFunc = handlerFunc
+	info.handlers = group.combineHandlers(handlerFunc)
+	group.engine.addRoute(info)
+	group.lastPath = absolutePath
+	group.lastRoutes = append(group.lastRoutes, RouteInfo{Method: httpMethod, Path: absolutePath})
+}
+
 // func (group *RouterGroup) makeHandlers(
 // 	method, fullPath string, args []interface{}, routeHandler ...interface{}) []func(*Context) {
 // 	handlers := make([]func(*Context), 0, len(group.Handlers)+1)
@@ -65,6 +121,94 @@ func (group *RouterGroup) Use(middleware ...HandlerFunc) Group {
 	return group.returnObj()
 }
 
+// NoRoute adds handlers for the requests which can't be routed and whose path is under the group's
+// base path (and whose host matches the group's host, for a group created by Engine.Host).
+// The handlers run after the group middleware, the group with the longest matching base path wins.
+// It returns a 404 code by default.
+func (group *RouterGroup) NoRoute(handlers ...HandlerFunc) {
+	engine := group.engine
+	prefix := strings.TrimSuffix(group.basePath, "/")
+	for i, scoped := range engine.scopedNoRoutes {
+		if scoped.host == group.host && scoped.prefix == prefix {
+			engine.scopedNoRoutes[i].handlers = group.combineHandlers(handlers...)
+			return
+		}
+	}
+	engine.scopedNoRoutes = append(engine.scopedNoRoutes, scopedHandlers{
+		host:     group.host,
+		prefix:   prefix,
+		handlers: group.combineHandlers(handlers...),
+	})
+}
+
+// scopedHandlers are handlers which apply to the requests under a host and a path prefix.
+type scopedHandlers struct {
+	host     string
+	prefix   string
+	handlers HandlersChain
+}
+
+// noRouteHandlers returns the NoRoute handlers of the most specific group for host and path,
+// or the engine's ones if there are none.
+func (engine *Engine) noRouteHandlers(host, path string) HandlersChain {
+	var best *scopedHandlers
+	for i := range engine.scopedNoRoutes {
+		scoped := &engine.scopedNoRoutes[i]
+		if scoped.host != "" && scoped.host != host ||
+			path != scoped.prefix && !strings.HasPrefix(path, scoped.prefix+"/") {
+			continue
+		}
+		if best == nil || scoped.host != "" && best.host == "" ||
+			scoped.host == best.host && len(scoped.prefix) > len(best.prefix) {
+			best = scoped
+		}
+	}
+	if best == nil {
+		return engine.allNoRoute
+	}
+	return best.handlers
+}
+
+// Handle registers a new request handle with the given path and method.
+// The handler is either a func(*Context) or a reflective func(req, resp), like for GET.
+//
+// For GET, POST, PUT, PATCH and DELETE requests the respective shortcut
+// functions can be used.
+//
+// This function is intended for bulk loading and to allow the usage of less
+// frequently used, non-standardized or custom methods (e.g. for internal
+// communication with a proxy).
+func (group *RouterGroup) Handle(httpMethod, relativePath string, handler interface{}) Group {
+	assertMethod(httpMethod)
+	return group.handle(httpMethod, relativePath, handler)
+}
+
+func assertMethod(httpMethod string) {
+	if matched := regEnLetter.MatchString(httpMethod); !matched {
+		panic("http method " + httpMethod + " is not valid")
+	}
+}
+
+// Any registers a route that matches all the HTTP methods.
+// GET, POST, PUT, PATCH, HEAD, OPTIONS, DELETE, CONNECT, TRACE.
+func (group *RouterGroup) Any(relativePath string, handler interface{}) Group {
+	group.lastRoutes = nil
+	for _, method := range anyMethods {
+		group.handleRoute(method, relativePath, handler)
+	}
+	return group.returnObj()
+}
+
+// Match registers a route that matches the specified methods that you declared.
+func (group *RouterGroup) Match(methods []string, relativePath string, handler interface{}) Group {
+	group.lastRoutes = nil
+	for _, method := range methods {
+		assertMethod(method)
+		group.handleRoute(method, relativePath, handler)
+	}
+	return group.returnObj()
+}
+
 // POST is a shortcut for router.Handle("POST", path, handle).
 func (group *RouterGroup) POST(relativePath string, handler interface{}) Group {
 	return group.handle(http.MethodPost, relativePath, handler)
@@ -107,6 +251,8 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 		Handlers: group.combineHandlers(handlers...),
 		basePath: group.calculateAbsolutePath(relativePath),
 		engine:   group.engine,
+		host:     group.host,
+		version:  group.version,
 	}
 }
 
@@ -3,8 +3,11 @@ package hapi
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/hookya/hapi/internal/bytesconv"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
@@ -25,13 +28,81 @@ func (c HandlersChain) Last() HandlerFunc {
 
 type Engine struct {
 	RouterGroup
-	pool   sync.Pool
-	trees  methodTrees
-	UseH2C bool
+	pool           sync.Pool
+	noRoute        HandlersChain
+	noMethod       HandlersChain
+	allNoRoute     HandlersChain
+	allNoMethod    HandlersChain
+	scopedNoRoutes []scopedHandlers // NoRoute handlers registered on groups
+	table          atomic.Value     // the *routeTable serving the requests
+	builder        *routeTable      // the table modified by registration, nil when routes can't be registered
+	frozen         bool             // set by Freeze, the table serving the requests is compiled
+	mu             sync.Mutex       // serialises Freeze and UpdateRoutes
+	mounted        []*Engine        // the engines mounted by Mount, frozen along with this one
+	constraints    map[string]ParamConstraint
+	codecs         map[string]Codec // by media type
+	rules          map[string]ValidationRule
+	UseH2C         bool
+
+	// HandleMethodNotAllowed if enabled, the router checks if another method is allowed for the
+	// current route, if the current request can not be routed.
+	// If this is the case, the request is answered with 'Method Not Allowed'
+	// and HTTP status code 405 and an 'Allow' header listing the allowed methods.
+	// If no other Method is allowed, the request is answered with 'Not Found'.
+	HandleMethodNotAllowed bool
+
+	// RedirectTrailingSlash enables automatic redirection if the current route can't be matched but a
+	// handler for the path with (without) the trailing slash exists.
+	// For example if /foo/ is requested but a route only exists for /foo, the
+	// client is redirected to /foo with http status code 301 for GET requests
+	// and 308 for all other request methods.
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath if enabled, the router tries to fix the current request path, if no
+	// handle is registered for it.
+	// First superfluous path elements like ../ or // are removed.
+	// Afterwards the router does a case-insensitive lookup of the cleaned path.
+	// If a handle can be found for this route, the router makes a redirection
+	// to the corrected path with status code 301 for GET requests and 308 for
+	// all other request methods.
+	// For example /FOO and /..//Foo could be redirected to /foo.
+	// RedirectTrailingSlash is independent of this option.
+	RedirectFixedPath bool
+
+	// UseRawPath if enabled, the url.RawPath is used to find the route and its parameters,
+	// so that a parameter can hold an escaped '/' (%2F). It is only used when set by
+	// the url package, i.e. when the path has such escapes.
+	UseRawPath bool
+
+	// UnescapePathValues if true, the values of the path parameters found in url.RawPath are unescaped.
+	// Without UseRawPath the url.Path, which is already unescaped, is used.
+	UnescapePathValues bool
+
+	// RemoveExtraSlash if enabled, the path is cleaned before the route is found:
+	// duplicate slashes are removed and '.' and '..' elements are resolved,
+	// e.g. /a//b/../c is routed as /a/c.
+	RemoveExtraSlash bool
+
+	// VersionSelector selects the version requested by a request for the routes registered through
+	// RouterGroup.Version. It defaults to the value of the 'Api-Version' header.
+	VersionSelector VersionSelector
+
+	// DefaultVersion is the version of the requests which don't ask for one.
+	DefaultVersion string
+
+	// MaxMultipartMemory is the maxMemory param given to http.Request's ParseMultipartForm
+	// to bind a multipart body, the files beyond it are stored in temporary files.
+	MaxMultipartMemory int64
+
+	// BindingErrorHandler renders the error of a request which can't be bound to the req parameter of
+	// a handler, the handler isn't called. By default the error is rendered by Context.Data, with status 400.
+	BindingErrorHandler func(c *Context, err *BindingError)
 }
 
 var _ Group = &Engine{}
 
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
 var (
 	default404Body = []byte(`{"code":"404","message":"Not Found."}`)
 	default405Body = []byte(`{"code":"405","message":"method not allowed."}`)
@@ -45,10 +116,25 @@ func New() *Engine {
 			basePath: "/",
 			root:     false,
 		},
-		trees:  make(methodTrees, 0, 7),
-		UseH2C: true,
+		builder:                &routeTable{},
+		constraints:            make(map[string]ParamConstraint, len(defaultConstraints)),
+		codecs:                 make(map[string]Codec, len(defaultCodecs)),
+		UseH2C:                 true,
+		HandleMethodNotAllowed: true,
+		RedirectTrailingSlash:  true,
+		RedirectFixedPath:      false,
+		UnescapePathValues:     true,
+		MaxMultipartMemory:     defaultMultipartMemory,
+		VersionSelector:        HeaderVersion("Api-Version"),
 	}
 	engine.RouterGroup.engine = engine
+	engine.table.Store(engine.builder)
+	for name, constraint := range defaultConstraints {
+		engine.constraints[name] = constraint
+	}
+	for mediaType, codec := range defaultCodecs {
+		engine.codecs[mediaType] = codec
+	}
 	engine.pool.New = func() any {
 		return engine.allocateContext()
 	}
@@ -62,7 +148,10 @@ func Default() *Engine {
 }
 
 func (engine *Engine) allocateContext() *Context {
-	return &Context{engine: engine}
+	table := engine.loadTable()
+	v := make(Params, 0, table.maxParams)
+	skippedNodes := make([]skippedNode, 0, table.maxSections)
+	return &Context{engine: engine, params: &v, skippedNodes: &skippedNodes}
 }
 
 // Use attaches a global middleware to the router. i.e. the middleware attached through Use() will be
@@ -70,31 +159,54 @@ func (engine *Engine) allocateContext() *Context {
 // For example, this is the right place for a logger or error management middleware.
 func (engine *Engine) Use(middleware ...HandlerFunc) Group {
 	engine.RouterGroup.Use(middleware...)
-	// engine.rebuild404Handlers()
-	// engine.rebuild405Handlers()
+	engine.rebuild404Handlers()
+	engine.rebuild405Handlers()
 	return engine
 }
 
-func (engine *Engine) addRoute(method, path string, handlers HandlersChain) {
-	assert1(path[0] == '/', "path must begin with '/'")
-	assert1(method != "", "HTTP method can not be empty")
-	assert1(len(handlers) > 0, "there must be at least one handler")
+// NoRoute adds handlers for NoRoute. It returns a 404 code by default.
+// The handlers run after the global middleware attached through Use().
+func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
+	engine.noRoute = handlers
+	engine.rebuild404Handlers()
+}
+
+// NoMethod sets the handlers called when Engine.HandleMethodNotAllowed = true.
+// The handlers run after the global middleware attached through Use().
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.noMethod = handlers
+	engine.rebuild405Handlers()
+}
 
-	debugPrintRoute(method, path, handlers)
+func (engine *Engine) rebuild404Handlers() {
+	engine.allNoRoute = engine.combineHandlers(engine.noRoute...)
+}
 
-	root := engine.trees.get(method)
-	if root == nil {
-		root = new(node)
-		root.fullPath = "/"
-		engine.trees = append(engine.trees, methodTree{method: method, root: root})
+func (engine *Engine) rebuild405Handlers() {
+	engine.allNoMethod = engine.combineHandlers(engine.noMethod...)
+}
+
+// addRoute adds route to the table of the builder, route.handlers is its whole chain.
+func (engine *Engine) addRoute(route RouteInfo) {
+	assert1(route.Path[0] == '/', "path must begin with '/'")
+	assert1(route.Method != "", "HTTP method can not be empty")
+	assert1(len(route.handlers) > 0, "there must be at least one handler")
+
+	engine.assertNotFrozen("route '" + route.Method + " " + route.Host + route.Path + "'")
+
+	if route.Version == "" {
+		debugPrintRoute(route.Method, route.Host+route.Path, route.handlers)
+	} else {
+		debugPrintRoute(route.Method, route.Host+route.Path+" (v"+route.Version+")", route.handlers)
 	}
-	root.addRoute(path, handlers)
+	engine.builder.add(route, engine.constraints)
 }
 
-// Run attaches the router to a http.Server and starts listening and serving HTTP requests.
-// It is a shortcut for http.ListenAndServe(addr, router)
+// Run freezes the engine, attaches the router to a http.Server and starts listening and serving HTTP requests.
+// It is a shortcut for router.Freeze() and http.ListenAndServe(addr, router)
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 func (engine *Engine) Run(addr string) (err error) {
+	engine.Freeze()
 
 	address := resolveAddress(addr)
 	debugPrint("Listening and serving HTTP on %s\n", address)
@@ -104,17 +216,22 @@ func (engine *Engine) Run(addr string) (err error) {
 
 // ServeHTTP conforms to the http.Handler interface.
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	engine.serve(w, req, nil)
+}
 
+// serve handles req with a Context from the pool, whose Keys are keys.
+func (engine *Engine) serve(w http.ResponseWriter, req *http.Request, keys map[string]any) {
 	c := engine.pool.Get().(*Context)
 	c.writermem.reset(w)
 	c.Request = req
 	c.reset()
+	c.Keys = keys
 	defer func() {
-		err := recover()
-		if err != nil {
+		if err := recover(); err != nil {
 			fmt.Println(err)
+			c.handlers = nil
+			serveError(c, http.StatusInternalServerError, default500Body)
 		}
-		serveError(c, http.StatusInternalServerError, default500Body)
 	}()
 	engine.handleHTTPRequest(c)
 	engine.pool.Put(c)
@@ -132,32 +249,105 @@ func (engine *Engine) Handler() http.Handler {
 func (engine *Engine) handleHTTPRequest(c *Context) {
 	httpMethod := c.Request.Method
 	rPath := c.Request.URL.Path
+	unescape := false
+	if engine.UseRawPath && len(c.Request.URL.RawPath) > 0 {
+		rPath = c.Request.URL.RawPath
+		unescape = engine.UnescapePathValues
+	}
+	if engine.RemoveExtraSlash {
+		rPath = cleanPath(rPath)
+	}
 
-	// Find root of the tree for the given HTTP method
-	t := engine.trees
-	for i, tl := 0, len(t); i < tl; i++ {
-		if t[i].method != httpMethod {
-			continue
+	table := engine.loadTable()
+	if cap(*c.params) < int(table.maxParams) {
+		// the routes were updated since the context was allocated
+		params := make(Params, 0, table.maxParams)
+		c.params = &params
+	}
+	if cap(*c.skippedNodes) < int(table.maxSections) {
+		skippedNodes := make([]skippedNode, 0, table.maxSections)
+		c.skippedNodes = &skippedNodes
+	}
+
+	t, host := &table.methods, ""
+	if len(table.hosts) > 0 {
+		if h := matchHost(table, c); h != nil {
+			t, host = &h.trees, h.host
+			c.Params = *c.params
 		}
-		root := t[i].root
+	}
+
+	// Find root of the tree for the given HTTP method
+	hostParams := len(*c.params)
+	if root := t.get(httpMethod); root != nil {
 		// Find route in tree
-		handlers := root.getValue(rPath)
-		if handlers != nil {
-			c.handlers = handlers
-			// c.fullPath = fullPath
+		value := root.getValue(rPath, c.params, c.skippedNodes, unescape)
+		if value.params != nil {
+			c.Params = *value.params
+		}
+		if value.handlers != nil {
+			c.handlers = value.handlers
+			c.fullPath = value.fullPath
 			c.Next()
 			c.writermem.WriteHeaderNow()
 			return
 		}
-		break
+		if httpMethod != http.MethodConnect && rPath != "/" {
+			if value.tsr && engine.RedirectTrailingSlash {
+				redirectTrailingSlash(c)
+				return
+			}
+			if engine.RedirectFixedPath && redirectFixedPath(c, root, engine.RedirectTrailingSlash) {
+				return
+			}
+		}
 	}
 
+	// The requests of the methods without a matching route are served by the mounted handlers, see Mount
+	if root := t.get(anyMethod); root != nil {
+		*c.params = (*c.params)[:hostParams]
+		*c.skippedNodes = (*c.skippedNodes)[:0]
+		if value := root.getValue(rPath, c.params, c.skippedNodes, unescape); value.handlers != nil {
+			if value.params != nil {
+				c.Params = *value.params
+			}
+			c.handlers = value.handlers
+			c.fullPath = value.fullPath
+			c.Next()
+			c.writermem.WriteHeaderNow()
+			return
+		}
+	}
+
+	if engine.HandleMethodNotAllowed {
+		if allowed := engine.allowedMethods(c, t.trees, rPath); len(allowed) > 0 {
+			c.handlers = engine.allNoMethod
+			c.writermem.Header().Set("Allow", strings.Join(allowed, ", "))
+			serveError(c, http.StatusMethodNotAllowed, default405Body)
+			return
+		}
+	}
+	c.handlers = engine.noRouteHandlers(host, rPath)
 	serveError(c, http.StatusNotFound, default404Body)
 }
 
+// allowedMethods returns the methods, other than the request's one, which have a route matching path.
+func (engine *Engine) allowedMethods(c *Context, trees methodTrees, path string) (allowed []string) {
+	for _, tree := range trees {
+		if tree.method == c.Request.Method {
+			continue
+		}
+		*c.skippedNodes = (*c.skippedNodes)[:0]
+		if value := tree.root.getValue(path, nil, c.skippedNodes, false); value.handlers != nil {
+			allowed = append(allowed, tree.method)
+		}
+	}
+	return
+}
+
 func serveError(c *Context, code int, defaultMessage []byte) {
 	c.writermem.status = code
-	// c.Next()
+	c.Next()
 	if c.writermem.Written() {
 		return
 	}
@@ -171,3 +361,46 @@ func serveError(c *Context, code int, defaultMessage []byte) {
 	}
 	c.writermem.WriteHeaderNow()
 }
+
+func redirectTrailingSlash(c *Context) {
+	req := c.Request
+	req.URL.Path = toggleTrailingSlash(req.URL.Path)
+	if req.URL.RawPath != "" {
+		req.URL.RawPath = toggleTrailingSlash(req.URL.RawPath)
+	}
+	redirectRequest(c)
+}
+
+// toggleTrailingSlash removes the trailing slash of p, or adds one if there is none.
+func toggleTrailingSlash(p string) string {
+	if length := len(p); length > 1 && p[length-1] == '/' {
+		return p[:length-1]
+	}
+	return p + "/"
+}
+
+func redirectFixedPath(c *Context, root *node, trailingSlash bool) bool {
+	req := c.Request
+	rPath := req.URL.Path
+
+	if fixedPath, ok := root.findCaseInsensitivePath(cleanPath(rPath), trailingSlash); ok {
+		req.URL.Path = bytesconv.BytesToString(fixedPath)
+		redirectRequest(c)
+		return true
+	}
+	return false
+}
+
+func redirectRequest(c *Context) {
+	req := c.Request
+	rPath := req.URL.Path
+	rURL := req.URL.String()
+
+	code := http.StatusMovedPermanently // Permanent redirect, request with GET method
+	if req.Method != http.MethodGet {
+		code = http.StatusPermanentRedirect
+	}
+	debugPrint("redirecting request %d: %s --> %s", code, rPath, rURL)
+	http.Redirect(c.Writer, req, rURL, code)
+	c.writermem.WriteHeaderNow()
+}
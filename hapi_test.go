@@ -0,0 +1,651 @@
+package hapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func init() {
+	SetMode(TestMode)
+}
+
+type header struct {
+	Key   string
+	Value string
+}
+
+func performRequest(r http.Handler, method, path string, headers ...header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for _, h := range headers {
+		req.Header.Add(h.Key, h.Value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouteParam(t *testing.T) {
+	router := New()
+	var name string
+	router.GET("/user/:name", func(c *Context) {
+		name = c.Param("name")
+	})
+
+	w := performRequest(router, http.MethodGet, "/user/hapi")
+	if w.Code != http.StatusOK {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if name != "hapi" {
+		t.Errorf("param name: %q", name)
+	}
+}
+
+func TestRouteParamBinding(t *testing.T) {
+	router := New()
+	users := router.Group("/users/:uid")
+	users.GET("/posts/:id", func(req *struct {
+		Param struct {
+			Uid string
+			Id  int64 `json:"id"`
+		}
+	}, resp *struct {
+		Data interface{}
+	}) {
+		resp.Data = req.Param
+	})
+
+	w := performRequest(router, http.MethodGet, "/users/u1/posts/42")
+	if w.Code != http.StatusOK {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if body := w.Body.String(); body != `{"code":0,"message":"success","data":{"Uid":"u1","id":42}}`+"\n" {
+		t.Errorf("body: %s", body)
+	}
+}
+
+func TestRouteParamBindingUnknownName(t *testing.T) {
+	router := New()
+	if recv := catchPanic(func() {
+		router.GET("/users/:id", func(req *struct {
+			Param struct {
+				Name string `json:"name"`
+			}
+		}, resp *struct{}) {
+		})
+	}); recv == nil {
+		t.Error("no panic for req.Param field without path param")
+	}
+}
+
+func TestRouteCatchAllBinding(t *testing.T) {
+	router := New()
+	router.GET("/static/*filepath", func(req *struct {
+		Param struct {
+			Filepath string `json:"filepath"`
+		}
+	}, resp *struct {
+		Data string
+	}) {
+		resp.Data = req.Param.Filepath
+	})
+
+	w := performRequest(router, http.MethodGet, "/static/css/app.css")
+	if body := w.Body.String(); body != `{"code":0,"message":"success","data":"/css/app.css"}`+"\n" {
+		t.Errorf("body: %s", body)
+	}
+}
+
+func TestRouteNotAllowed(t *testing.T) {
+	router := New()
+	router.POST("/path", func(c *Context) {})
+	router.PUT("/path", func(c *Context) {})
+	router.GET("/other", func(c *Context) {})
+
+	w := performRequest(router, http.MethodGet, "/path")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "POST, PUT" {
+		t.Errorf("Allow header: %q", allow)
+	}
+
+	w = performRequest(router, http.MethodGet, "/none")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status code: %d", w.Code)
+	}
+
+	router.HandleMethodNotAllowed = false
+	w = performRequest(router, http.MethodGet, "/path")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "" {
+		t.Errorf("Allow header: %q", allow)
+	}
+}
+
+func TestRouteRedirectTrailingSlash(t *testing.T) {
+	router := New()
+	router.GET("/path", func(c *Context) {})
+	router.GET("/path2/", func(c *Context) {})
+	router.POST("/path3", func(c *Context) {})
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/path/", http.StatusMovedPermanently, "/path"},
+		{http.MethodGet, "/path2", http.StatusMovedPermanently, "/path2/"},
+		{http.MethodPost, "/path3/", http.StatusPermanentRedirect, "/path3"},
+	}
+	for _, test := range tests {
+		w := performRequest(router, test.method, test.path)
+		if w.Code != test.code {
+			t.Errorf("%s %s: status code %d", test.method, test.path, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%s %s: location %q", test.method, test.path, location)
+		}
+	}
+
+	router.RedirectTrailingSlash = false
+	w := performRequest(router, http.MethodGet, "/path/")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status code: %d", w.Code)
+	}
+}
+
+func TestRouteRedirectFixedPath(t *testing.T) {
+	router := New()
+	router.RedirectFixedPath = true
+	router.GET("/users", func(c *Context) {})
+	router.PUT("/users/:id", func(c *Context) {})
+	router.GET("/a/new", func(c *Context) {})
+	router.GET("/a/:id", func(c *Context) {})
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/USERS", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/Users/", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/../users", http.StatusMovedPermanently, "/users"},
+		{http.MethodPut, "/Users/Ab", http.StatusPermanentRedirect, "/users/Ab"},
+		{http.MethodGet, "/A/x", http.StatusMovedPermanently, "/a/x"},
+		{http.MethodGet, "/A/NEW", http.StatusMovedPermanently, "/a/new"},
+		{http.MethodGet, "/A/new", http.StatusMovedPermanently, "/a/new"},
+	}
+	for _, test := range tests {
+		w := performRequest(router, test.method, test.path)
+		if w.Code != test.code {
+			t.Errorf("%s %s: status code %d", test.method, test.path, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%s %s: location %q", test.method, test.path, location)
+		}
+	}
+}
+
+func TestRouteRawPath(t *testing.T) {
+	router := New()
+	var name string
+	router.GET("/files/:name", func(c *Context) { name = c.Param("name") })
+	router.GET("/codes/:code<alpha>", func(c *Context) { name = c.Param("code") })
+	router.GET("/dirs/:name/", func(c *Context) {})
+	sub := New()
+	sub.UseRawPath = true
+	sub.GET("/:name", func(c *Context) { name = c.Param("name") })
+	router.Mount("/sub", sub)
+
+	tests := []struct {
+		useRawPath bool
+		unescape   bool
+		path       string
+		code       int
+		name       string
+	}{
+		{false, true, "/files/a%2Fb", http.StatusNotFound, ""},
+		{true, true, "/files/a%2Fb", http.StatusOK, "a/b"},
+		{true, false, "/files/a%2Fb", http.StatusOK, "a%2Fb"},
+		{true, true, "/files/a%20b", http.StatusOK, "a b"},
+		{true, true, "/codes/%41", http.StatusOK, "A"},
+		{false, true, "/sub/a%2Fb", http.StatusOK, "a/b"},
+	}
+	for _, test := range tests {
+		name = ""
+		router.UseRawPath, router.UnescapePathValues = test.useRawPath, test.unescape
+		w := performRequest(router, http.MethodGet, test.path)
+		if w.Code != test.code {
+			t.Errorf("%s: status code %d", test.path, w.Code)
+		}
+		if name != test.name {
+			t.Errorf("%s: param %q", test.path, name)
+		}
+	}
+
+	router.UseRawPath = true
+	w := performRequest(router, http.MethodGet, "/dirs/a%2Fb")
+	if location := w.Header().Get("Location"); location != "/dirs/a%2Fb/" {
+		t.Errorf("location: %q", location)
+	}
+}
+
+func TestRouteRemoveExtraSlash(t *testing.T) {
+	router := New()
+	router.RemoveExtraSlash = true
+	var name string
+	router.GET("/files/:name", func(c *Context) { name = c.Param("name") })
+
+	for path, code := range map[string]int{
+		"/files/a":           http.StatusOK,
+		"//files//a":         http.StatusOK,
+		"/x/../files/./a":    http.StatusOK,
+		"/files/../files/..": http.StatusNotFound,
+	} {
+		name = ""
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("%s: status code %d", path, w.Code)
+		}
+		if code == http.StatusOK && name != "a" {
+			t.Errorf("%s: param %q", path, name)
+		}
+	}
+}
+
+func TestRouteConstraint(t *testing.T) {
+	router := New()
+	router.Constraint("even", func(value string) bool {
+		return len(value) > 0 && (value[len(value)-1]-'0')%2 == 0
+	})
+	router.GET("/numbers/:n<even>", func(c *Context) {})
+
+	if w := performRequest(router, http.MethodGet, "/numbers/42"); w.Code != http.StatusOK {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if w := performRequest(router, http.MethodGet, "/numbers/43"); w.Code != http.StatusNotFound {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if recv := catchPanic(func() { router.Constraint("even", nil) }); recv == nil {
+		t.Error("no panic for nil constraint")
+	}
+	if recv := catchPanic(func() {
+		router.Constraint("int", func(string) bool { return true })
+	}); recv == nil {
+		t.Error("no panic for duplicated constraint")
+	}
+}
+
+func TestRouteHost(t *testing.T) {
+	router := New()
+	var route string
+	router.GET("/", func(c *Context) { route = "default" })
+	router.Host("api.example.com").GET("/", func(c *Context) { route = "api" })
+	router.Host(":tenant.example.com").GET("/users/:id", func(req *struct {
+		Param struct {
+			Tenant string `json:"tenant"`
+			Id     int    `json:"id"`
+		}
+	}, resp *struct{}) {
+		route = req.Param.Tenant + "/" + strconv.Itoa(req.Param.Id)
+	})
+
+	tests := []struct {
+		host  string
+		path  string
+		code  int
+		route string
+	}{
+		{"example.com", "/", http.StatusOK, "default"},
+		{"api.example.com", "/", http.StatusOK, "api"},
+		{"API.example.com:8080", "/", http.StatusOK, "api"},
+		{"acme.example.com", "/users/7", http.StatusOK, "acme/7"},
+		{"acme.example.com", "/", http.StatusNotFound, ""},
+		{"a.b.example.com", "/users/7", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		route = ""
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Host = test.host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s%s: status code %d", test.host, test.path, w.Code)
+		}
+		if route != test.route {
+			t.Errorf("%s%s: route %q", test.host, test.path, route)
+		}
+	}
+
+	if recv := catchPanic(func() {
+		router.Host(":id.example.com").GET("/:id", func(c *Context) {})
+	}); recv == nil {
+		t.Error("no panic for param conflicting with host")
+	}
+	if recv := catchPanic(func() { router.Host("api..example.com") }); recv == nil {
+		t.Error("no panic for invalid host")
+	}
+	if recv := catchPanic(func() { router.Host("api.example.com:8080") }); recv == nil {
+		t.Error("no panic for host with port")
+	}
+}
+
+func TestRouteHostParam(t *testing.T) {
+	router := New()
+	var tenant, id string
+	router.Host(":tenant.example.com").GET("/users/:id", func(c *Context) {
+		tenant, id = c.Param("tenant"), c.Param("id")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Host = "acme.example.com"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if tenant != "acme" || id != "7" {
+		t.Errorf("params: %q %q", tenant, id)
+	}
+}
+
+func TestNoRouteWithMiddleware(t *testing.T) {
+	router := New()
+	var middleware bool
+	router.Use(func(c *Context) {
+		middleware = true
+		c.Writer.Header().Set("X-Middleware", "1")
+	})
+	router.GET("/path", func(c *Context) {})
+
+	w := performRequest(router, http.MethodGet, "/none")
+	if w.Code != http.StatusNotFound || !middleware || w.Header().Get("X-Middleware") != "1" {
+		t.Errorf("status code: %d, middleware: %t", w.Code, middleware)
+	}
+	if body := w.Body.String(); body != string(default404Body) {
+		t.Errorf("body: %s", body)
+	}
+
+	router.NoRoute(func(c *Context) {
+		c.StatusJson(http.StatusNotFound, "custom 404")
+	})
+	w = performRequest(router, http.MethodGet, "/none")
+	if w.Code != http.StatusNotFound || w.Header().Get("X-Middleware") != "1" {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if body := w.Body.String(); body != "\"custom 404\"\n" {
+		t.Errorf("body: %s", body)
+	}
+}
+
+func TestNoMethodWithMiddleware(t *testing.T) {
+	router := New()
+	router.NoMethod(func(c *Context) {
+		c.StatusJson(http.StatusMethodNotAllowed, "custom 405")
+	})
+	router.Use(func(c *Context) {
+		c.Writer.Header().Set("X-Middleware", "1")
+	})
+	router.POST("/path", func(c *Context) {})
+
+	w := performRequest(router, http.MethodGet, "/path")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("X-Middleware") != "1" {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if body := w.Body.String(); body != "\"custom 405\"\n" {
+		t.Errorf("body: %s", body)
+	}
+}
+
+func TestGroupNoRoute(t *testing.T) {
+	router := New()
+	router.NoRoute(func(c *Context) { c.StatusJson(http.StatusNotFound, "root") })
+	api := router.Group("/api", func(c *Context) {
+		c.Writer.Header().Set("X-Api", "1")
+	})
+	api.NoRoute(func(c *Context) { c.StatusJson(http.StatusNotFound, "api") })
+	api.Group("/v2").NoRoute(func(c *Context) { c.StatusJson(http.StatusNotFound, "v2") })
+	router.Host("admin.example.com").NoRoute(func(c *Context) { c.StatusJson(http.StatusNotFound, "admin") })
+	router.Host("admin.example.com").GET("/", func(c *Context) {})
+
+	tests := []struct {
+		host string
+		path string
+		body string
+		api  bool
+	}{
+		{"example.com", "/none", "root", false},
+		{"example.com", "/apiary", "root", false},
+		{"example.com", "/api", "api", true},
+		{"example.com", "/api/none", "api", true},
+		{"example.com", "/api/v2/none", "v2", true},
+		{"admin.example.com", "/api/none", "admin", false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Host = test.host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s%s: status code %d", test.host, test.path, w.Code)
+		}
+		if body := w.Body.String(); body != `"`+test.body+`"`+"\n" {
+			t.Errorf("%s%s: body %s", test.host, test.path, body)
+		}
+		if api := w.Header().Get("X-Api") == "1"; api != test.api {
+			t.Errorf("%s%s: group middleware %t", test.host, test.path, api)
+		}
+	}
+}
+
+func TestPanicServesError(t *testing.T) {
+	router := New()
+	var after bool
+	router.Use(func(c *Context) {
+		c.Next()
+		after = true
+	})
+	router.GET("/panic", func(c *Context) { panic("panic") })
+
+	w := performRequest(router, http.MethodGet, "/panic")
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status code: %d", w.Code)
+	}
+	if after {
+		t.Error("middleware resumed after panic")
+	}
+}
+
+func TestRouteHandleAnyMatch(t *testing.T) {
+	router := New()
+	var method string
+	router.Handle("PROPFIND", "/dav", func(c *Context) { method = c.Request.Method })
+	router.Any("/any", func(req *struct {
+		Ctx *Context
+	}, resp *struct{}) {
+		method = req.Ctx.Request.Method
+	})
+	router.Match([]string{http.MethodGet, "REPORT"}, "/match", func(c *Context) { method = c.Request.Method })
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{"PROPFIND", "/dav", http.StatusOK},
+		{http.MethodGet, "/dav", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/any", http.StatusOK},
+		{http.MethodDelete, "/any", http.StatusOK},
+		{http.MethodTrace, "/any", http.StatusOK},
+		{"REPORT", "/match", http.StatusOK},
+		{http.MethodGet, "/match", http.StatusOK},
+		{http.MethodPost, "/match", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		method = ""
+		w := performRequest(router, test.method, test.path)
+		if w.Code != test.code {
+			t.Errorf("%s %s: status code %d", test.method, test.path, w.Code)
+		}
+		if test.code == http.StatusOK && method != test.method {
+			t.Errorf("%s %s: handled method %q", test.method, test.path, method)
+		}
+	}
+
+	if recv := catchPanic(func() {
+		router.Handle("get", "/lower", func(c *Context) {})
+	}); recv == nil {
+		t.Error("no panic for invalid method")
+	}
+}
+
+func TestMount(t *testing.T) {
+	legacy := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Path", req.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	sub := New()
+	sub.GET("/users/:id", func(c *Context) {
+		c.Writer.Header().Set("X-Path", c.Request.URL.Path)
+		c.Writer.Header().Set("X-User", c.GetString("user")+"/"+c.Param("id"))
+		c.Set("sub", true)
+	})
+
+	router := New()
+	var subKey bool
+	api := router.Group("/api", func(c *Context) {
+		c.Set("user", "u1")
+		c.Writer.Header().Set("X-Middleware", "1")
+		c.Next()
+		subKey = c.GetBool("sub")
+	})
+	api.Mount("/legacy", legacy)
+	api.Mount("/sub/", sub)
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		realPath string
+	}{
+		{http.MethodPost, "/api/legacy", http.StatusAccepted, "/"},
+		{http.MethodPost, "/api/legacy/", http.StatusAccepted, "/"},
+		{http.MethodPost, "/api/legacy/a/b?x=1", http.StatusAccepted, "/a/b"},
+		{http.MethodGet, "/api/sub/users/7", http.StatusOK, "/users/7"},
+		{http.MethodGet, "/api/sub/none", http.StatusNotFound, ""},
+		{"PROPFIND", "/api/legacy/files/abc", http.StatusAccepted, "/files/abc"},
+		{"PROPFIND", "/api/legacy", http.StatusAccepted, "/"},
+		{"PROPFIND", "/api/sub/users/7", http.StatusMethodNotAllowed, ""},
+	}
+	for _, test := range tests {
+		w := performRequest(router, test.method, test.path)
+		if w.Code != test.code {
+			t.Errorf("%s: status code %d", test.path, w.Code)
+		}
+		if path := w.Header().Get("X-Path"); path != test.realPath {
+			t.Errorf("%s: mounted path %q", test.path, path)
+		}
+		if w.Header().Get("X-Middleware") != "1" {
+			t.Errorf("%s: group middleware not run", test.path)
+		}
+	}
+
+	w := performRequest(router, http.MethodGet, "/api/sub/users/7")
+	if user := w.Header().Get("X-User"); user != "u1/7" {
+		t.Errorf("X-User: %q", user)
+	}
+	if !subKey {
+		t.Error("key set by mounted engine not visible")
+	}
+
+	router.Handle("PROPFIND", "/api/legacy/own", func(c *Context) { c.Writer.Header().Set("X-Path", "own") })
+	if w := performRequest(router, "PROPFIND", "/api/legacy/own"); w.Header().Get("X-Path") != "own" {
+		t.Errorf("route of the method: X-Path %q", w.Header().Get("X-Path"))
+	}
+	if w := performRequest(router, "PROPFIND", "/none"); w.Code != http.StatusNotFound {
+		t.Errorf("not mounted: status code %d", w.Code)
+	}
+}
+
+func TestRouteVersion(t *testing.T) {
+	router := New()
+	router.DefaultVersion = "1"
+	var version string
+	router.Version("1").GET("/users", func(c *Context) { version = "1" })
+	router.Version("2").GET("/users", func(c *Context) { version = "2" })
+	router.Version("2").POST("/users", func(c *Context) { version = "2" })
+
+	tests := []struct {
+		method  string
+		version string
+		code    int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodGet, "1", http.StatusOK},
+		{http.MethodGet, "2", http.StatusOK},
+		{http.MethodGet, "3", http.StatusNotAcceptable},
+		{http.MethodPost, "2", http.StatusOK},
+		{http.MethodPost, "", http.StatusNotAcceptable},
+	}
+	for _, test := range tests {
+		version = ""
+		w := performRequest(router, test.method, "/users", header{"Api-Version", test.version})
+		if w.Code != test.code {
+			t.Errorf("%s %q: status code %d", test.method, test.version, w.Code)
+		}
+		want := test.version
+		if want == "" && test.code == http.StatusOK {
+			want = "1"
+		}
+		if test.code == http.StatusOK && version != want {
+			t.Errorf("%s %q: version %q", test.method, test.version, version)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Api-Version" {
+			t.Errorf("%s %q: Vary %q", test.method, test.version, vary)
+		}
+	}
+
+	if recv := catchPanic(func() {
+		router.Version("2").GET("/users", func(c *Context) {})
+	}); recv == nil {
+		t.Error("no panic for duplicated version")
+	}
+	if recv := catchPanic(func() {
+		router.GET("/users", func(c *Context) {})
+	}); recv == nil {
+		t.Error("no panic for unversioned route conflicting with versioned ones")
+	}
+}
+
+func TestMediaTypeVersion(t *testing.T) {
+	router := New()
+	router.VersionSelector = MediaTypeVersion("acme")
+	var version string
+	router.Version("1").GET("/users", func(c *Context) { version = "1" })
+	router.Version("2").GET("/users", func(c *Context) { version = "2" })
+
+	tests := []struct {
+		accept  string
+		version string
+	}{
+		{"application/vnd.acme.v2+json", "2"},
+		{"text/html, application/vnd.acme.v1+json; q=0.9", "1"},
+		{"application/vnd.other.v2+json", ""},
+		{"application/json", ""},
+	}
+	for _, test := range tests {
+		version = ""
+		w := performRequest(router, http.MethodGet, "/users", header{"Accept", test.accept})
+		if version != test.version {
+			t.Errorf("%q: version %q", test.accept, version)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("%q: Vary %q", test.accept, vary)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package hapi
+
+import (
+	"net"
+	"strings"
+)
+
+// hostTree holds the routes registered for a host pattern like "api.example.com" or ":tenant.example.com".
+type hostTree struct {
+	host     string
+	segments []string
+	trees    methodTable
+}
+
+type hostTrees []*hostTree
+
+// Host creates a new router group whose routes are only matched for requests to host.
+// A host segment starting with ':' matches any value and is captured as a param,
+// e.g. ":tenant.example.com" makes c.Param("tenant") available to the handlers.
+// Requests for hosts without a matching pattern are routed by the routes registered on the engine itself.
+// The port of a request is ignored, so host can't have one.
+func (engine *Engine) Host(host string, handlers ...HandlerFunc) *RouterGroup {
+	host = strings.ToLower(host)
+	validateHost(host)
+	return &RouterGroup{
+		Handlers: engine.combineHandlers(handlers...),
+		basePath: engine.basePath,
+		engine:   engine,
+		host:     host,
+	}
+}
+
+func validateHost(host string) {
+	assert1(host != "", "host can not be empty")
+	names := map[string]bool{}
+	for _, segment := range strings.Split(host, ".") {
+		assert1(segment != "", "empty segment in host '"+host+"'")
+		if segment[0] != ':' {
+			if strings.Contains(segment, ":") {
+				panic("host '" + host + "' can not have a port, the port of the requests is ignored")
+			}
+			continue
+		}
+		name := segment[1:]
+		if name == "" || strings.ContainsAny(name, ":*<") {
+			panic("invalid wildcard '" + segment + "' in host '" + host + "'")
+		}
+		if names[name] {
+			panic("duplicated wildcard '" + segment + "' in host '" + host + "'")
+		}
+		names[name] = true
+	}
+}
+
+func hostParamNames(host string) (names []string) {
+	if host == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(host, ".") {
+		if segment[0] == ':' {
+			names = append(names, segment[1:])
+		}
+	}
+	return
+}
+
+// matchHost returns the host tree for the host of the request, nil if there is none.
+// An exact host wins over a pattern, patterns are tried in the order they were registered.
+// The values of host wildcards are appended to c.params.
+func matchHost(table *routeTable, c *Context) *hostTree {
+	host := strings.ToLower(requestHost(c.Request.Host))
+	if h := table.hosts[host]; h != nil {
+		return h
+	}
+	segments := strings.Split(host, ".")
+	for _, h := range table.patterns {
+		if len(h.segments) != len(segments) {
+			continue
+		}
+		matched := true
+		for i, segment := range h.segments {
+			if segment[0] != ':' && segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for i, segment := range h.segments {
+			if segment[0] == ':' {
+				*c.params = append(*c.params, Param{Key: segment[1:], Value: segments[i]})
+			}
+		}
+		return h
+	}
+	return nil
+}
+
+// requestHost strips the port from the Host of a request.
+func requestHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
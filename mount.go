@@ -0,0 +1,87 @@
+package hapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountParam is the name of the catch-all param of the route matching the paths below a mount prefix.
+const mountParam = "hapi_mount"
+
+// anyMethod is the method of the routes of Mount serving the requests of the methods other than anyMethods,
+// those of the methods without a route matching the request, e.g. "PROPFIND".
+const anyMethod = "*"
+
+// Mount serves all the requests under relativePath by handler, for any method: the standard methods
+// are registered as routes, and the requests of other methods, like the WebDAV ones, are served by
+// handler when no route of their method matches them. The latter routes are listed with method "*".
+// The prefix is stripped from the request path before it is passed to handler,
+// and the group middleware run before it as for any route.
+// If handler is an Engine, its handlers share the Context keys of the mounting Engine.
+func (group *RouterGroup) Mount(relativePath string, handler http.Handler) Group {
+	assert1(handler != nil, "mounted handler can not be nil")
+	absolutePath := strings.TrimSuffix(group.calculateAbsolutePath(relativePath), "/")
+
+	sections := strings.Count(absolutePath, "/")
+	handlerFunc := func(c *Context) {
+		// the path is taken from the escaped path, so that a mounted Engine using UseRawPath gets the raw path
+		path := c.Request.URL.EscapedPath()
+		if c.engine.RemoveExtraSlash {
+			path = cleanPath(path)
+		}
+		path = mountedPath(path, sections)
+		req := c.Request.Clone(c.Request.Context())
+		req.URL.RawPath = path
+		req.URL.Path, _ = url.PathUnescape(path)
+		req.RequestURI = req.URL.RequestURI()
+
+		if engine, ok := handler.(*Engine); ok {
+			engine.serveMounted(c, req)
+		} else {
+			handler.ServeHTTP(c.Writer, req)
+		}
+	}
+	info := RouteInfo{Handler: fmt.Sprintf("%T", handler)}
+	group.lastRoutes = nil
+	for _, method := range append(anyMethods[:len(anyMethods):len(anyMethods)], anyMethod) {
+		if absolutePath != "" {
+			group.addRoute(method, absolutePath, handlerFunc, info)
+		}
+		group.addRoute(method, absolutePath+"/*"+mountParam, handlerFunc, info)
+	}
+	if engine, ok := handler.(*Engine); ok {
+		group.engine.mounted = append(group.engine.mounted, engine)
+		if group.engine.frozen {
+			engine.Freeze() // mounted in UpdateRoutes
+		}
+	}
+	return group.returnObj()
+}
+
+// mountedPath returns the part of path after the sections of the mount prefix.
+// A param of the prefix can't hold a '/' in the escaped path, so counting the slashes is enough.
+func mountedPath(path string, sections int) string {
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			if sections == 0 {
+				return path[i:]
+			}
+			sections--
+		}
+	}
+	return "/"
+}
+
+// serveMounted serves req which was routed to the engine by parent, see RouterGroup.Mount.
+func (engine *Engine) serveMounted(parent *Context, req *http.Request) {
+	parent.mu.Lock()
+	if parent.Keys == nil {
+		parent.Keys = make(map[string]any)
+	}
+	keys := parent.Keys
+	parent.mu.Unlock()
+
+	engine.serve(parent.Writer, req, keys)
+}
@@ -0,0 +1,160 @@
+package hapi
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// RouteInfo represents a registered route.
+type RouteInfo struct {
+	Method string
+	Host   string // empty for the routes matching any host
+	Path   string
+	// Version is the API version the route is registered for, see RouterGroup.Version.
+	Version string
+	// Name is the name given to the route by RouterGroup.Name.
+	Name string
+	// Handler is the name of the route handler, as passed to GET, POST...
+	Handler string
+	// Middlewares are the names of the group middleware, in the order they run before Handler.
+	Middlewares []string
+	// Req and Resp are the req and resp types of a typed or reflective handler, nil for a func(*Context).
+	Req         reflect.Type
+	Resp        reflect.Type
+	HandlerFunc HandlerFunc
+	handlers    HandlersChain // the group middleware followed by HandlerFunc
+}
+
+// Routes returns the registered routes, in the order they were registered.
+func (engine *Engine) Routes() []RouteInfo {
+	table := engine.loadTable()
+	routes := make([]RouteInfo, len(table.routes))
+	copy(routes, table.routes)
+	return routes
+}
+
+// Remove removes the route registered through the group for httpMethod and relativePath,
+// all its versions for a group without version. It reports whether there was such a route.
+// Once the engine is frozen, routes can only be removed in Engine.UpdateRoutes.
+func (group *RouterGroup) Remove(httpMethod, relativePath string) bool {
+	engine := group.engine
+	absolutePath := group.calculateAbsolutePath(relativePath)
+	engine.assertNotFrozen("route '" + httpMethod + " " + group.host + absolutePath + "'")
+
+	routes := make([]RouteInfo, 0, len(engine.builder.routes))
+	for _, route := range engine.builder.routes {
+		if route.Method == httpMethod && route.Host == group.host && route.Path == absolutePath &&
+			(group.version == "" || route.Version == group.version) {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	if len(routes) == len(engine.builder.routes) {
+		return false
+	}
+	debugPrint("removed route %s %s%s\n", httpMethod, group.host, absolutePath)
+	engine.setBuilder(engine.rebuildTable(routes))
+	return true
+}
+
+// namedRoute is a route registered with a name, used to build its URL.
+type namedRoute struct {
+	path        string
+	constraints map[string]ParamConstraint
+}
+
+// Name names the routes registered by the last GET, POST... Any, Match or Mount call on the group, so that its URL can be built by
+// Engine.URL and Context.URLFor. It panics if the name is already used by another route.
+//
+//	router.GET("/users/:id", showUser).Name("user.show")
+func (group *RouterGroup) Name(name string) Group {
+	assert1(name != "", "route name can not be empty")
+	assert1(group.lastPath != "", "there is no route to name '"+name+"'")
+	engine := group.engine
+	engine.assertNotFrozen("name of route '" + group.host + group.lastPath + "'")
+	engine.builder.nameRoute(name, group.lastPath, engine.constraints)
+	for i := range engine.builder.routes {
+		route := &engine.builder.routes[i]
+		if route.Name != "" || route.Host != group.host || route.Version != group.version {
+			continue
+		}
+		for _, last := range group.lastRoutes {
+			if route.Method == last.Method && route.Path == last.Path {
+				route.Name = name
+			}
+		}
+	}
+	return group.returnObj()
+}
+
+func (t *routeTable) nameRoute(name, path string, constraints map[string]ParamConstraint) {
+	if route, ok := t.names[name]; ok {
+		if route.path == path {
+			return // the same path registered for another method
+		}
+		panic("route name '" + name + "' is already registered for path '" + route.path + "'")
+	}
+	route := namedRoute{path: path}
+	for p := path; ; {
+		wildcard, i, _ := findWildcard(p)
+		if i < 0 {
+			break
+		}
+		if name, constraint := splitParamConstraint(wildcard); constraint != "" {
+			if route.constraints == nil {
+				route.constraints = map[string]ParamConstraint{}
+			}
+			route.constraints[name] = compileConstraint(constraint, path, constraints)
+		}
+		p = p[i+len(wildcard):]
+	}
+	if t.names == nil {
+		t.names = make(map[string]namedRoute)
+	}
+	t.names[name] = route
+}
+
+// URL builds the path of the route named name. params are the values of the route's wildcards,
+// in the order they appear in the path. An error is returned if the route doesn't exist,
+// the number of params doesn't match or a value doesn't satisfy its constraint.
+//
+//	router.URL("user.show", 42) // "/users/42"
+func (engine *Engine) URL(name string, params ...interface{}) (string, error) {
+	route, ok := engine.loadTable().names[name]
+	if !ok {
+		return "", fmt.Errorf("hapi: no route named '%s'", name)
+	}
+	if count := len(pathParamNames(route.path)); count != len(params) {
+		return "", fmt.Errorf("hapi: route '%s' requires %d params, got %d", name, count, len(params))
+	}
+
+	var b strings.Builder
+	path := route.path
+	for _, param := range params {
+		wildcard, i, _ := findWildcard(path)
+		paramName, _ := splitParamConstraint(wildcard)
+		value := fmt.Sprint(param)
+		if constraint := route.constraints[paramName]; constraint != nil && !constraint(value) {
+			return "", fmt.Errorf("hapi: param '%s' of route '%s' doesn't satisfy its constraint: %s", paramName, name, value)
+		}
+		if wildcard[0] == '*' {
+			// the value of a catch-all starts with the '/' before it
+			b.WriteString(path[:i-1])
+			if !strings.HasPrefix(value, "/") {
+				b.WriteByte('/')
+			}
+			b.WriteString(value)
+		} else {
+			if value == "" {
+				return "", fmt.Errorf("hapi: param '%s' of route '%s' can not be empty", paramName, name)
+			}
+			b.WriteString(path[:i])
+			b.WriteString(url.PathEscape(value))
+		}
+		path = path[i+len(wildcard):]
+	}
+	b.WriteString(path)
+	return b.String(), nil
+}
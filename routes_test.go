@@ -0,0 +1,136 @@
+package hapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestEngineURL(t *testing.T) {
+	router := New()
+	router.GET("/users/:id<int>", func(c *Context) {}).Name("user.show")
+	router.GET("/users", func(c *Context) {}).Name("user.list").
+		POST("/users", func(c *Context) {}).Name("user.list")
+	router.Group("/files").GET("/:dir/*filepath", func(c *Context) {}).Name("file")
+	router.GET("/names/:name<[a-z]*:?>", func(c *Context) {}).Name("name")
+
+	tests := []struct {
+		name   string
+		params []interface{}
+		url    string
+		err    bool
+	}{
+		{"user.show", []interface{}{42}, "/users/42", false},
+		{"user.show", []interface{}{"abc"}, "", true},
+		{"user.show", nil, "", true},
+		{"user.show", []interface{}{1, 2}, "", true},
+		{"user.list", nil, "/users", false},
+		{"file", []interface{}{"a b", "/x/y.png"}, "/files/a%20b/x/y.png", false},
+		{"file", []interface{}{"a", "y.png"}, "/files/a/y.png", false},
+		{"file", []interface{}{"", "y.png"}, "", true},
+		{"name", []interface{}{"abc"}, "/names/abc", false},
+		{"name", []interface{}{"ABC"}, "", true},
+		{"none", nil, "", true},
+	}
+	for _, test := range tests {
+		url, err := router.URL(test.name, test.params...)
+		if url != test.url || (err != nil) != test.err {
+			t.Errorf("URL(%q, %v) = %q, %v", test.name, test.params, url, err)
+		}
+	}
+
+	if max := router.loadTable().maxParams; max != 2 {
+		t.Errorf("maxParams: %d", max)
+	}
+
+	if recv := catchPanic(func() {
+		router.GET("/other", func(c *Context) {}).Name("user.show")
+	}); recv == nil {
+		t.Error("no panic for duplicated route name")
+	}
+	if recv := catchPanic(func() { router.Group("/g").Name("empty") }); recv == nil {
+		t.Error("no panic for naming without route")
+	}
+}
+
+func TestRouterGroupName(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", func(c *Context) {})
+	router.Group("/users").PUT("/:id", func(c *Context) {})
+	router.DELETE("/users/:id", func(c *Context) {}).Name("user.delete")
+	router.Match([]string{http.MethodGet, http.MethodPost}, "/items", func(c *Context) {}).Name("items")
+	router.Remove(http.MethodPost, "/items")
+
+	names := map[string]string{}
+	for _, route := range router.Routes() {
+		names[route.Method+" "+route.Path] = route.Name
+	}
+	expected := map[string]string{
+		"GET /users/:id": "", "PUT /users/:id": "", "DELETE /users/:id": "user.delete", "GET /items": "items",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("names: %v", names)
+	}
+}
+
+func TestContextURLFor(t *testing.T) {
+	router := New()
+	var url string
+	router.GET("/users/:id", func(c *Context) {
+		url, _ = c.URLFor("user.show", c.Param("id"))
+	}).Name("user.show")
+
+	performRequest(router, http.MethodGet, "/users/7")
+	if url != "/users/7" {
+		t.Errorf("url: %q", url)
+	}
+}
+
+func routesTestMiddleware(c *Context) {}
+
+func routesTestHandler(c *Context) {}
+
+type routesTestReq struct {
+	Query struct {
+		Page int `json:"page"`
+	}
+}
+
+type routesTestResp struct {
+	Data []string
+}
+
+func routesTestTypedHandler(req *routesTestReq, resp *routesTestResp) {}
+
+func TestEngineRoutes(t *testing.T) {
+	router := New()
+	router.Use(routesTestMiddleware)
+	router.GET("/", routesTestHandler)
+	router.Host("api.example.com").POST("/users", routesTestTypedHandler)
+
+	routes := router.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("routes: %d", len(routes))
+	}
+
+	r := routes[0]
+	if r.Method != http.MethodGet || r.Host != "" || r.Path != "/" ||
+		r.Handler != "github.com/hookya/hapi.routesTestHandler" || r.Req != nil || r.Resp != nil {
+		t.Errorf("route: %+v", r)
+	}
+	if len(r.Middlewares) != 1 || r.Middlewares[0] != "github.com/hookya/hapi.routesTestMiddleware" {
+		t.Errorf("middlewares: %v", r.Middlewares)
+	}
+
+	r = routes[1]
+	if r.Method != http.MethodPost || r.Host != "api.example.com" || r.Path != "/users" ||
+		r.Handler != "github.com/hookya/hapi.routesTestTypedHandler" {
+		t.Errorf("route: %+v", r)
+	}
+	if r.Req != reflect.TypeOf(&routesTestReq{}) || r.Resp != reflect.TypeOf(&routesTestResp{}) {
+		t.Errorf("types: %v %v", r.Req, r.Resp)
+	}
+	if r.HandlerFunc == nil {
+		t.Error("nil HandlerFunc")
+	}
+}
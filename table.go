@@ -0,0 +1,254 @@
+package hapi
+
+import (
+	"net/http"
+	"strings"
+)
+
+// stdMethodsCount is the number of methods methodIndex knows.
+const stdMethodsCount = 9
+
+// methodIndex returns the position of a standard HTTP method in methodTable.std, -1 for other methods.
+func methodIndex(method string) int {
+	switch method {
+	case http.MethodGet:
+		return 0
+	case http.MethodHead:
+		return 1
+	case http.MethodPost:
+		return 2
+	case http.MethodPut:
+		return 3
+	case http.MethodPatch:
+		return 4
+	case http.MethodDelete:
+		return 5
+	case http.MethodConnect:
+		return 6
+	case http.MethodOptions:
+		return 7
+	case http.MethodTrace:
+		return 8
+	}
+	return -1
+}
+
+// methodTable holds the route trees of the engine or of a host, one per method.
+type methodTable struct {
+	trees methodTrees // in the order the methods were registered
+	std   [stdMethodsCount]*node
+}
+
+// get returns the root of the tree of method, nil if no route is registered for it.
+func (t *methodTable) get(method string) *node {
+	if i := methodIndex(method); i >= 0 {
+		return t.std[i]
+	}
+	return t.trees.get(method)
+}
+
+// root returns the root of the tree of method, adding an empty tree if there is none.
+func (t *methodTable) root(method string) *node {
+	root := t.get(method)
+	if root == nil {
+		root = &node{fullPath: "/"}
+		t.trees = append(t.trees, methodTree{method: method, root: root})
+		if i := methodIndex(method); i >= 0 {
+			t.std[i] = root
+		}
+	}
+	return root
+}
+
+func (t *methodTable) compile() methodTable {
+	compiled := methodTable{trees: make(methodTrees, len(t.trees))}
+	for i, tree := range t.trees {
+		root := tree.root.compile()
+		compiled.trees[i] = methodTree{method: tree.method, root: root}
+		if j := methodIndex(tree.method); j >= 0 {
+			compiled.std[j] = root
+		}
+	}
+	return compiled
+}
+
+// routeTable holds all the routes of an engine. Registration modifies the table of Engine.builder,
+// the table serving the requests is only replaced, see Engine.UpdateRoutes.
+type routeTable struct {
+	methods     methodTable                // routes matching any host
+	hosts       map[string]*hostTree       // by host pattern
+	patterns    hostTrees                  // the host patterns with wildcards, in the order they were registered
+	versioned   map[string]*versionedRoute // routes registered through RouterGroup.Version
+	names       map[string]namedRoute
+	routes      []RouteInfo // in the order they were registered
+	maxParams   uint16
+	maxSections uint16
+}
+
+// add adds route to the table, its handlers are the whole chain of the route.
+func (t *routeTable) add(route RouteInfo, constraints map[string]ParamConstraint) {
+	if route.Version == "" {
+		t.addRoute(route.Host, route.Method, route.Path, route.handlers, constraints)
+	} else {
+		t.addVersionedRoute(route.Host, route.Method, route.Path, route.Version, route.handlers, constraints)
+	}
+	if route.Name != "" {
+		t.nameRoute(route.Name, route.Path, constraints)
+	}
+	t.routes = append(t.routes, route)
+}
+
+func (t *routeTable) addRoute(host, method, path string, handlers HandlersChain, constraints map[string]ParamConstraint) {
+	trees := &t.methods
+	if host != "" {
+		for _, name := range pathParamNames(path) {
+			for _, hostName := range hostParamNames(host) {
+				assert1(name != hostName, "param '"+name+"' in path '"+path+"' conflicts with host '"+host+"'")
+			}
+		}
+		trees = &t.host(host).trees
+	}
+	trees.root(method).addRoute(path, handlers, constraints)
+
+	// Update maxParams
+	if paramsCount := uint16(len(hostParamNames(host)) + len(pathParamNames(path))); paramsCount > t.maxParams {
+		t.maxParams = paramsCount
+	}
+
+	if sectionsCount := countSections(path); sectionsCount > t.maxSections {
+		t.maxSections = sectionsCount
+	}
+}
+
+// host returns the tree of a host pattern, adding an empty one if there is none.
+func (t *routeTable) host(host string) *hostTree {
+	if h := t.hosts[host]; h != nil {
+		return h
+	}
+	h := &hostTree{host: host, segments: strings.Split(host, ".")}
+	if t.hosts == nil {
+		t.hosts = make(map[string]*hostTree)
+	}
+	t.hosts[host] = h
+	if strings.Contains(host, ":") {
+		t.patterns = append(t.patterns, h)
+	}
+	return h
+}
+
+// compile returns a copy of the table whose nodes have a lookup array for their children.
+// Nothing modifies the copy afterwards, so it can be read concurrently.
+func (t *routeTable) compile() *routeTable {
+	compiled := &routeTable{
+		methods:     t.methods.compile(),
+		names:       t.names,
+		routes:      t.routes,
+		maxParams:   t.maxParams,
+		maxSections: t.maxSections,
+	}
+	if len(t.hosts) > 0 {
+		compiled.hosts = make(map[string]*hostTree, len(t.hosts))
+	}
+	for _, h := range t.hosts {
+		compiled.hosts[h.host] = &hostTree{host: h.host, segments: h.segments, trees: h.trees.compile()}
+	}
+	for _, h := range t.patterns {
+		compiled.patterns = append(compiled.patterns, compiled.hosts[h.host])
+	}
+	return compiled
+}
+
+// compile returns a deep copy of the tree, in which children are found through a lookup array
+// rather than by a scan of the indices.
+func (n *node) compile() *node {
+	compiled := *n
+	compiled.children = make([]*node, len(n.children))
+	for i, child := range n.children {
+		compiled.children[i] = child.compile()
+	}
+	if len(n.indices) > 1 && len(n.indices) < 256 {
+		compiled.lookup = new([256]uint8)
+		for i := 0; i < len(n.indices); i++ {
+			compiled.lookup[n.indices[i]] = uint8(i + 1)
+		}
+	}
+	return &compiled
+}
+
+// Freeze compiles the registered routes into an immutable table optimised for lookups,
+// which serves the requests from then on. Registering a route afterwards panics,
+// routes can only be changed through UpdateRoutes.
+// Run freezes the engine itself, call Freeze once all the routes are registered when the
+// engine is served another way, e.g. by a http.Server. Mounted engines are frozen too.
+func (engine *Engine) Freeze() {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.freeze()
+}
+
+func (engine *Engine) freeze() {
+	if engine.frozen {
+		return
+	}
+	engine.table.Store(engine.builder.compile())
+	engine.builder = nil
+	engine.frozen = true
+	for _, mounted := range engine.mounted {
+		mounted.Freeze()
+	}
+}
+
+// UpdateRoutes changes the routes of the engine while it serves requests. The routes registered and
+// removed by update, through any group of the engine, apply to a copy of the routes which replaces
+// the ones serving the requests once update returns. In-flight requests keep the routes they were
+// matched with, and none of the changes apply if update panics.
+// Updates are serialised, the engine is frozen first if it isn't yet. Routes must only be registered
+// and removed within update once the engine serves requests: a registration made concurrently by
+// another goroutine outside UpdateRoutes isn't detected, it races with the update and ends in its routes.
+//
+//	router.UpdateRoutes(func() {
+//		admin.Remove(http.MethodGet, "/plugins/old")
+//		admin.GET("/plugins/new", newPlugin)
+//	})
+func (engine *Engine) UpdateRoutes(update func()) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.freeze()
+
+	engine.builder = engine.rebuildTable(engine.loadTable().routes)
+	defer func() {
+		engine.builder = nil
+	}()
+	update()
+	engine.table.Store(engine.builder.compile())
+}
+
+// rebuildTable returns a new table holding routes.
+func (engine *Engine) rebuildTable(routes []RouteInfo) *routeTable {
+	t := &routeTable{routes: make([]RouteInfo, 0, len(routes))}
+	for _, route := range routes {
+		t.add(route, engine.constraints)
+	}
+	return t
+}
+
+// setBuilder replaces the table modified by registration, which serves the requests until the engine is frozen.
+func (engine *Engine) setBuilder(t *routeTable) {
+	engine.builder = t
+	if !engine.frozen {
+		engine.table.Store(t)
+	}
+}
+
+// loadTable returns the table serving the requests.
+func (engine *Engine) loadTable() *routeTable {
+	return engine.table.Load().(*routeTable)
+}
+
+// assertNotFrozen panics if the routes can't be changed. It doesn't lock Engine.mu, which UpdateRoutes
+// holds while the routes are changed, so it is only reliable for the goroutine serialised with updates.
+func (engine *Engine) assertNotFrozen(what string) {
+	if engine.builder == nil {
+		panic("can not change " + what + ": the engine is frozen, use UpdateRoutes")
+	}
+}
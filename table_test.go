@@ -0,0 +1,243 @@
+package hapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func Test_node_compile(t *testing.T) {
+	tree := &node{}
+	routes := [...]string{
+		"/",
+		"/a",
+		"/b/",
+		"/c/:id",
+		"/c/new",
+		"/d/*filepath",
+		"/e",
+		"/f/:id<int>",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route), nil)
+	}
+
+	compiled := tree.compile()
+	if compiled.lookup == nil {
+		t.Fatal("no lookup array for the root")
+	}
+	checkRequests(t, compiled, testRequests{
+		{"/", false, "/", nil},
+		{"/a", false, "/a", nil},
+		{"/b", true, "", nil},
+		{"/b/", false, "/b/", nil},
+		{"/c/7", false, "/c/:id", Params{Param{"id", "7"}}},
+		{"/c/new", false, "/c/new", nil},
+		{"/d/x/y", false, "/d/*filepath", Params{Param{"filepath", "/x/y"}}},
+		{"/e", false, "/e", nil},
+		{"/f/7", false, "/f/:id<int>", Params{Param{"id", "7"}}},
+		{"/f/x", true, "", nil},
+		{"/g", true, "", nil},
+	})
+	if value := compiled.getValue("/b", nil, getSkippedNodes(), false); !value.tsr {
+		t.Error("no trailing slash recommendation for /b")
+	}
+
+	// the compiled tree is a copy
+	tree.addRoute("/g", fakeHandler("/g"), nil)
+	checkRequests(t, compiled, testRequests{{"/g", true, "", nil}})
+}
+
+func TestEngineFreeze(t *testing.T) {
+	sub := New()
+	sub.GET("/", func(c *Context) {})
+
+	router := New()
+	router.GET("/users/:id", func(c *Context) {})
+	router.Handle("PURGE", "/cache", func(c *Context) {})
+	router.Host(":tenant.example.com").GET("/", func(c *Context) {
+		c.Writer.Header().Set("X-Tenant", c.Param("tenant"))
+	})
+	router.Mount("/sub", sub)
+	router.Freeze()
+	router.Freeze()
+
+	tests := []struct {
+		method string
+		host   string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "example.com", "/users/7", http.StatusOK},
+		{http.MethodPost, "example.com", "/users/7", http.StatusMethodNotAllowed},
+		{"PURGE", "example.com", "/cache", http.StatusOK},
+		{http.MethodGet, "example.com", "/sub", http.StatusOK},
+		{http.MethodGet, "acme.example.com", "/", http.StatusOK},
+		{http.MethodGet, "example.com", "/none", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		req.Host = test.host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s %s%s: status code %d", test.method, test.host, test.path, w.Code)
+		}
+	}
+
+	for name, register := range map[string]func(){
+		"route":           func() { router.GET("/new", func(c *Context) {}) },
+		"host route":      func() { router.Host("api.example.com").GET("/", func(c *Context) {}) },
+		"versioned route": func() { router.Version("2").GET("/users/:id", func(c *Context) {}) },
+		"mounted route":   func() { sub.GET("/new", func(c *Context) {}) },
+	} {
+		if recv := catchPanic(register); recv == nil {
+			t.Errorf("no panic for %s registered after Freeze", name)
+		}
+	}
+}
+
+func TestEngineUpdateRoutes(t *testing.T) {
+	router := New()
+	router.GET("/old", func(c *Context) {}).Name("old")
+	router.GET("/users/:id", func(c *Context) {}).Name("user")
+	router.Version("1").GET("/items", func(c *Context) {})
+	router.Host("api.example.com").GET("/old", func(c *Context) {})
+	if router.Remove(http.MethodGet, "/none") {
+		t.Error("missing route removed before Freeze")
+	}
+	router.Freeze()
+
+	if recv := catchPanic(func() { router.Remove(http.MethodGet, "/old") }); recv == nil {
+		t.Error("no panic for route removed after Freeze")
+	}
+
+	var removed, missing bool
+	router.UpdateRoutes(func() {
+		removed = router.Remove(http.MethodGet, "/old")
+		missing = router.Remove(http.MethodGet, "/none")
+		router.GET("/new/:a/:b/:c/:d/:e/:f", func(c *Context) {
+			c.Writer.Header().Set("X-F", c.Param("f"))
+		})
+		router.Version("2").GET("/items", func(c *Context) {})
+	})
+	if !removed || missing {
+		t.Errorf("removed: %v, missing removed: %v", removed, missing)
+	}
+
+	tests := []struct {
+		host    string
+		path    string
+		version string
+		code    int
+	}{
+		{"example.com", "/old", "", http.StatusNotFound},
+		{"api.example.com", "/old", "", http.StatusOK},
+		{"example.com", "/users/7", "", http.StatusOK},
+		{"example.com", "/new/1/2/3/4/5/6", "", http.StatusOK},
+		{"example.com", "/items", "1", http.StatusOK},
+		{"example.com", "/items", "2", http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Host = test.host
+		req.Header.Set("Api-Version", test.version)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s%s: status code %d", test.host, test.path, w.Code)
+		}
+	}
+	w := performRequest(router, http.MethodGet, "/new/1/2/3/4/5/6")
+	if f := w.Header().Get("X-F"); f != "6" {
+		t.Errorf("param f: %q", f)
+	}
+
+	if _, err := router.URL("old"); err == nil {
+		t.Error("URL built for a removed route")
+	}
+	if url, err := router.URL("user", 7); err != nil || url != "/users/7" {
+		t.Errorf("URL: %q, %v", url, err)
+	}
+	if routes := router.Routes(); len(routes) != 5 {
+		t.Errorf("routes: %d", len(routes))
+	}
+
+	if recv := catchPanic(func() {
+		router.UpdateRoutes(func() {
+			router.GET("/partial", func(c *Context) {})
+			router.GET("/users/:id", func(c *Context) {})
+		})
+	}); recv == nil {
+		t.Error("no panic for duplicated route")
+	}
+	if w := performRequest(router, http.MethodGet, "/partial"); w.Code != http.StatusNotFound {
+		t.Errorf("route of a failed update served: %d", w.Code)
+	}
+	if recv := catchPanic(func() { router.GET("/late", func(c *Context) {}) }); recv == nil {
+		t.Error("no panic for route registered after a failed update")
+	}
+}
+
+func TestEngineUpdateRoutesConcurrent(t *testing.T) {
+	router := New()
+	router.GET("/stable", func(c *Context) {})
+	plugins := router.Group("/plugins")
+
+	// several goroutines update the routes at the same time, each toggling its own route an even number of times
+	const updaters, updates = 4, 20
+	var updating sync.WaitGroup
+	for u := 0; u < updaters; u++ {
+		updating.Add(1)
+		go func(u int) {
+			defer updating.Done()
+			path := "/" + strconv.Itoa(u) + "/:id"
+			for i := 0; i < updates; i++ {
+				router.UpdateRoutes(func() {
+					if !plugins.Remove(http.MethodGet, path) {
+						plugins.GET(path, func(c *Context) {
+							c.Writer.Header().Set("X-Id", c.Param("id"))
+						})
+					}
+				})
+			}
+		}(u)
+	}
+	done := make(chan struct{})
+	go func() {
+		updating.Wait()
+		close(done)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < updaters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if w := performRequest(router, http.MethodGet, "/stable"); w.Code != http.StatusOK {
+					t.Errorf("/stable: status code %d", w.Code)
+				}
+				w := performRequest(router, http.MethodGet, "/plugins/"+strconv.Itoa(i)+"/x")
+				switch {
+				case w.Code == http.StatusOK && w.Header().Get("X-Id") != "x":
+					t.Errorf("param id: %q", w.Header().Get("X-Id"))
+				case w.Code != http.StatusOK && w.Code != http.StatusNotFound:
+					t.Errorf("/plugins/%d/x: status code %d", i, w.Code)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if routes := router.Routes(); len(routes) != 1 {
+		t.Errorf("routes after updates: %d", len(routes))
+	}
+}
@@ -68,3 +68,43 @@ func Traverse(
 	}
 	return true // go on traverse
 }
+
+// traverseTypeIndex traverses typ like TraverseType, passing fn the index of each field
+// from typ, through the embedded structs, for fieldByIndex.
+func traverseTypeIndex(typ reflect.Type, index []int, fn func(field reflect.StructField, index []int)) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldIndex := append(index[:len(index):len(index)], i)
+		if field.Anonymous {
+			fieldTyp := field.Type
+			if fieldTyp.Kind() == reflect.Ptr {
+				fieldTyp = fieldTyp.Elem()
+			}
+			if fieldTyp.Kind() == reflect.Struct {
+				traverseTypeIndex(fieldTyp, fieldIndex, fn)
+				continue
+			}
+		}
+		if field.Name[0] >= 'A' && field.Name[0] <= 'Z' {
+			fn(field, fieldIndex)
+		}
+	}
+}
+
+// fieldByIndex returns the field of val at index, allocating the nil embedded struct pointers
+// on the way like Traverse does.
+func fieldByIndex(val reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val
+}
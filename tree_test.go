@@ -1,6 +1,7 @@
 package hapi
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -11,6 +12,17 @@ type testRequests []struct {
 	path       string
 	nilHandler bool
 	route      string
+	ps         Params
+}
+
+func getParams() *Params {
+	ps := make(Params, 0, 20)
+	return &ps
+}
+
+func getSkippedNodes() *[]skippedNode {
+	ps := make([]skippedNode, 0, 20)
+	return &ps
 }
 
 func fakeHandler(val string) HandlersChain {
@@ -22,7 +34,8 @@ func fakeHandler(val string) HandlersChain {
 func checkRequests(t *testing.T, tree *node, requests testRequests) {
 
 	for _, request := range requests {
-		handlers := tree.getValue(request.path)
+		value := tree.getValue(request.path, getParams(), getSkippedNodes(), false)
+		handlers := value.handlers
 
 		if handlers == nil {
 			if !request.nilHandler {
@@ -36,6 +49,10 @@ func checkRequests(t *testing.T, tree *node, requests testRequests) {
 				t.Errorf("handle mismatch for route '%s': Wrong handle (%s != %s)", request.path, fakeHandlerValue, request.route)
 			}
 		}
+
+		if value.params != nil && !reflect.DeepEqual(*value.params, request.ps) {
+			t.Errorf("Params mismatch for route '%s'", request.path)
+		}
 	}
 }
 
@@ -56,20 +73,431 @@ func Test_node_addRoute(t *testing.T) {
 		"/β",
 	}
 	for _, route := range routes {
-		tree.addRoute(route, fakeHandler(route))
+		tree.addRoute(route, fakeHandler(route), nil)
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/a", false, "/a", nil},
+		{"/", true, "", nil},
+		{"/hi", false, "/hi", nil},
+		{"/contact", false, "/contact", nil},
+		{"/co", false, "/co", nil},
+		{"/con", true, "", nil},  // key mismatch
+		{"/cona", true, "", nil}, // key mismatch
+		{"/no", true, "", nil},   // no matching child
+		{"/ab", false, "/ab", nil},
+		{"/α", false, "/α", nil},
+		{"/β", false, "/β", nil},
+	})
+}
+
+func Test_node_wildcard(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/",
+		"/cmd/:tool/",
+		"/cmd/:tool/:sub",
+		"/cmd/whoami",
+		"/cmd/whoami/root",
+		"/cmd/whoami/root/",
+		"/src/*filepath",
+		"/search/",
+		"/search/:query",
+		"/search/hapi-hapi",
+		"/user_:name",
+		"/user_:name/about",
+		"/info/:user/public",
+		"/info/:user/project/:project",
+		"/info/:user/project/hapi",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route), nil)
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/", false, "/", nil},
+		{"/cmd/test", true, "", Params{Param{"tool", "test"}}},
+		{"/cmd/test/", false, "/cmd/:tool/", Params{Param{"tool", "test"}}},
+		{"/cmd/test/3", false, "/cmd/:tool/:sub", Params{Param{Key: "tool", Value: "test"}, Param{Key: "sub", Value: "3"}}},
+		{"/cmd/who", true, "", Params{Param{"tool", "who"}}},
+		{"/cmd/whoami", false, "/cmd/whoami", nil},
+		{"/cmd/whoami/root", false, "/cmd/whoami/root", nil},
+		{"/cmd/whoami/", true, "", nil},
+		{"/cmd/whoami/rooty", false, "/cmd/:tool/:sub", Params{Param{"tool", "whoami"}, Param{"sub", "rooty"}}},
+		{"/cmd/whoami/root/", false, "/cmd/whoami/root/", nil},
+		{"/src/", false, "/src/*filepath", Params{Param{"filepath", "/"}}},
+		{"/src/some/file.png", false, "/src/*filepath", Params{Param{"filepath", "/some/file.png"}}},
+		{"/search/", false, "/search/", nil},
+		{"/search/someth!ng+in+ünìcodé", false, "/search/:query", Params{Param{"query", "someth!ng+in+ünìcodé"}}},
+		{"/search/hapi-hapi", false, "/search/hapi-hapi", nil},
+		{"/search/hapi", false, "/search/:query", Params{Param{"query", "hapi"}}},
+		{"/user_hapi", false, "/user_:name", Params{Param{"name", "hapi"}}},
+		{"/user_hapi/about", false, "/user_:name/about", Params{Param{"name", "hapi"}}},
+		{"/info/hapi/public", false, "/info/:user/public", Params{Param{"user", "hapi"}}},
+		{"/info/hapi/project/go", false, "/info/:user/project/:project", Params{Param{"user", "hapi"}, Param{"project", "go"}}},
+		{"/info/hapi/project/hapi", false, "/info/:user/project/hapi", Params{Param{"user", "hapi"}}},
+	})
+}
+
+func testRoutes(t *testing.T, routes []struct {
+	path     string
+	conflict bool
+}) {
+	tree := &node{}
+
+	for _, route := range routes {
+		recv := catchPanic(func() {
+			tree.addRoute(route.path, fakeHandler(route.path), nil)
+		})
+
+		if route.conflict {
+			if recv == nil {
+				t.Errorf("no panic for conflicting route '%s'", route.path)
+			}
+		} else if recv != nil {
+			t.Errorf("unexpected panic for route '%s': %v", route.path, recv)
+		}
 	}
+}
+
+func catchPanic(testFunc func()) (recv any) {
+	defer func() {
+		recv = recover()
+	}()
+
+	testFunc()
+	return
+}
+
+func Test_node_wildcardConflict(t *testing.T) {
+	routes := []struct {
+		path     string
+		conflict bool
+	}{
+		{"/cmd/:tool/:sub", false},
+		{"/cmd/vet", false},
+		{"/cmd/*path", true},
+		{"/cmd/:badvar", true},
+		{"/cmd/:tool/names", false},
+		{"/cmd/:tool/:badsub/details", true},
+		{"/src/*filepath", false},
+		{"/src/:file", true},
+		{"/src/static.json", true},
+		{"/src/*filepathx", true},
+		{"/src/", true},
+		{"/src/foo/bar", true},
+		{"/src1/", false},
+		{"/src1/*filepath", true},
+		{"/src2*filepath", true},
+		{"/src2/*filepath", false},
+		{"/user_:name", false},
+		{"/user_x", false},
+		{"/user_:name", true},
+		{"/id:id", false},
+		{"/id/:id", false},
+		{"/:id", false},
+		{"/con:tact", false},
+		{"/conxxx", false},
+	}
+	testRoutes(t, routes)
+}
+
+func Test_node_invalidWildcard(t *testing.T) {
+	routes := []struct {
+		path     string
+		conflict bool
+	}{
+		{"/user/:", true},
+		{"/user/:id:name", true},
+		{"/src/*filepath/x", true},
+		{"/src2/", false},
+		{"/src2/*", true},
+		{"/src3/*filepath*x", true},
+		{"/user/:id/", false},
+	}
+	testRoutes(t, routes)
+}
+
+func Test_node_catchAllRoot(t *testing.T) {
+	tree := &node{}
+	tree.addRoute("/*filepath", fakeHandler("/*filepath"), nil)
 
 	checkRequests(t, tree, testRequests{
-		{"/a", false, "/a"},
-		{"/", true, ""},
-		{"/hi", false, "/hi"},
-		{"/contact", false, "/contact"},
-		{"/co", false, "/co"},
-		{"/con", true, ""},  // key mismatch
-		{"/cona", true, ""}, // key mismatch
-		{"/no", true, ""},   // no matching child
-		{"/ab", false, "/ab"},
-		{"/α", false, "/α"},
-		{"/β", false, "/β"},
+		{"/", false, "/*filepath", Params{Param{"filepath", "/"}}},
+		{"/static/app.js", false, "/*filepath", Params{Param{"filepath", "/static/app.js"}}},
 	})
 }
+
+func Test_node_trailingSlashRedirect(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/hi",
+		"/b/",
+		"/search/:query",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/x",
+		"/x/y",
+		"/y/",
+		"/y/z",
+		"/0/:id",
+		"/0/:id/1",
+		"/1/:id/",
+		"/1/:id/2",
+		"/aa",
+		"/a/",
+		"/doc",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/no/a",
+		"/no/b",
+		"/api/:page/:name",
+		"/api/hello/:name/bar/",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route), nil)
+	}
+
+	tsrRoutes := [...]string{
+		"/hi/",
+		"/b",
+		"/search/hapi/",
+		"/cmd/vet",
+		"/src",
+		"/x/",
+		"/y",
+		"/0/hapi/",
+		"/1/hapi",
+		"/a",
+		"/doc/",
+		"/api/hello/x/bar",
+	}
+	for _, route := range tsrRoutes {
+		value := tree.getValue(route, nil, getSkippedNodes(), false)
+		if value.handlers != nil {
+			t.Fatalf("non-nil handler for TSR route '%s", route)
+		} else if !value.tsr {
+			t.Errorf("expected TSR recommendation for route '%s'", route)
+		}
+	}
+
+	noTsrRoutes := [...]string{
+		"/",
+		"/no",
+		"/no/",
+		"/_",
+		"/_/",
+	}
+	for _, route := range noTsrRoutes {
+		value := tree.getValue(route, nil, getSkippedNodes(), false)
+		if value.handlers != nil {
+			t.Fatalf("non-nil handler for No-TSR route '%s", route)
+		} else if value.tsr {
+			t.Errorf("expected no TSR recommendation for route '%s'", route)
+		}
+	}
+}
+
+func Test_node_findCaseInsensitivePath(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/hi",
+		"/b/",
+		"/ABC/",
+		"/search/:query",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/x",
+		"/x/y",
+		"/y/",
+		"/y/z",
+		"/0/:id",
+		"/0/:id/1",
+		"/1/:id/",
+		"/1/:id/2",
+		"/aa",
+		"/a/",
+		"/doc",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/doc/go/away",
+		"/no/a",
+		"/no/b",
+		"/Π",
+		"/u/apfêl/",
+		"/u/äpfêl/",
+		"/u/öpfêl",
+		"/v/Äpfêl/",
+		"/v/Öpfêl",
+		"/w/♬",  // 3 byte
+		"/w/♭/", // 3 byte, last byte differs
+		"/w/𠜎",  // 4 byte
+		"/w/𠜏/", // 4 byte
+		"/z/new",
+		"/z/:id",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route), nil)
+	}
+
+	// Check out == in for all registered routes
+	// With fixTrailingSlash = true
+	for _, route := range routes {
+		out, found := tree.findCaseInsensitivePath(route, true)
+		if !found {
+			t.Errorf("Route '%s' not found!", route)
+		} else if string(out) != route {
+			t.Errorf("Wrong result for route '%s': %s", route, string(out))
+		}
+	}
+	// With fixTrailingSlash = false
+	for _, route := range routes {
+		out, found := tree.findCaseInsensitivePath(route, false)
+		if !found {
+			t.Errorf("Route '%s' not found!", route)
+		} else if string(out) != route {
+			t.Errorf("Wrong result for route '%s': %s", route, string(out))
+		}
+	}
+
+	tests := []struct {
+		in    string
+		out   string
+		found bool
+		slash bool
+	}{
+		{"/HI", "/hi", true, false},
+		{"/HI/", "/hi", true, true},
+		{"/B", "/b/", true, true},
+		{"/B/", "/b/", true, false},
+		{"/abc", "/ABC/", true, true},
+		{"/abc/", "/ABC/", true, false},
+		{"/aBc", "/ABC/", true, true},
+		{"/aBc/", "/ABC/", true, false},
+		{"/abC", "/ABC/", true, true},
+		{"/abC/", "/ABC/", true, false},
+		{"/SEARCH/QUERY", "/search/QUERY", true, false},
+		{"/SEARCH/QUERY/", "/search/QUERY", true, true},
+		{"/CMD/TOOL/", "/cmd/TOOL/", true, false},
+		{"/CMD/TOOL", "/cmd/TOOL/", true, true},
+		{"/SRC/FILE/PATH", "/src/FILE/PATH", true, false},
+		{"/x/Y", "/x/y", true, false},
+		{"/x/Y/", "/x/y", true, true},
+		{"/X/y", "/x/y", true, false},
+		{"/X/y/", "/x/y", true, true},
+		{"/X/Y", "/x/y", true, false},
+		{"/X/Y/", "/x/y", true, true},
+		{"/Y/", "/y/", true, false},
+		{"/Y", "/y/", true, true},
+		{"/Y/z", "/y/z", true, false},
+		{"/Y/z/", "/y/z", true, true},
+		{"/Y/Z", "/y/z", true, false},
+		{"/Y/Z/", "/y/z", true, true},
+		{"/y/Z", "/y/z", true, false},
+		{"/y/Z/", "/y/z", true, true},
+		{"/Aa", "/aa", true, false},
+		{"/Aa/", "/aa", true, true},
+		{"/AA", "/aa", true, false},
+		{"/AA/", "/aa", true, true},
+		{"/aA", "/aa", true, false},
+		{"/aA/", "/aa", true, true},
+		{"/A/", "/a/", true, false},
+		{"/A", "/a/", true, true},
+		{"/DOC", "/doc", true, false},
+		{"/DOC/", "/doc", true, true},
+		{"/NO", "", false, true},
+		{"/DOC/GO", "", false, true},
+		{"/π", "/Π", true, false},
+		{"/π/", "/Π", true, true},
+		{"/u/ÄPFÊL/", "/u/äpfêl/", true, false},
+		{"/u/ÄPFÊL", "/u/äpfêl/", true, true},
+		{"/u/ÖPFÊL/", "/u/öpfêl", true, true},
+		{"/u/ÖPFÊL", "/u/öpfêl", true, false},
+		{"/v/äpfêL/", "/v/Äpfêl/", true, false},
+		{"/v/äpfêL", "/v/Äpfêl/", true, true},
+		{"/v/öpfêL/", "/v/Öpfêl", true, true},
+		{"/v/öpfêL", "/v/Öpfêl", true, false},
+		{"/Z/NEW", "/z/new", true, false},
+		{"/Z/new/", "/z/new", true, true},
+		{"/Z/x", "/z/x", true, false},
+		{"/Z/NEWS", "/z/NEWS", true, false},
+		{"/w/♬/", "/w/♬", true, true},
+		{"/w/♭", "/w/♭/", true, true},
+		{"/w/𠜎/", "/w/𠜎", true, true},
+		{"/w/𠜏", "/w/𠜏/", true, true},
+	}
+	// With fixTrailingSlash = true
+	for _, test := range tests {
+		out, found := tree.findCaseInsensitivePath(test.in, true)
+		if found != test.found || (found && (string(out) != test.out)) {
+			t.Errorf("Wrong result for '%s': got %s, %t; want %s, %t",
+				test.in, string(out), found, test.out, test.found)
+		}
+	}
+	// With fixTrailingSlash = false
+	for _, test := range tests {
+		out, found := tree.findCaseInsensitivePath(test.in, false)
+		if test.slash {
+			if found { // test needs a trailingSlash fix. It must not be found!
+				t.Errorf("Found without fixTrailingSlash: %s; got %s", test.in, string(out))
+			}
+		} else {
+			if found != test.found || (found && (string(out) != test.out)) {
+				t.Errorf("Wrong result for '%s': got %s, %t; want %s, %t",
+					test.in, string(out), found, test.out, test.found)
+			}
+		}
+	}
+}
+
+func Test_node_constraint(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/orders/:id<int>",
+		"/orders/new",
+		"/orders/:id<int>/items",
+		"/files/:name<[a-z0-9-]+\\.pdf>",
+		"/files/readme",
+		"/re/:name<(?P<v>a{1,2})>/x",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route), nil)
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/orders/42", false, "/orders/:id<int>", Params{Param{"id", "42"}}},
+		{"/orders/-1", false, "/orders/:id<int>", Params{Param{"id", "-1"}}},
+		{"/orders/new", false, "/orders/new", nil},
+		{"/orders/newer", true, "", nil},
+		{"/orders/abc", true, "", nil},
+		{"/orders/42/items", false, "/orders/:id<int>/items", Params{Param{"id", "42"}}},
+		{"/orders/x/items", true, "", nil},
+		{"/files/report-1.pdf", false, "/files/:name<[a-z0-9-]+\\.pdf>", Params{Param{"name", "report-1.pdf"}}},
+		{"/files/report.txt", true, "", nil},
+		{"/files/readme", false, "/files/readme", nil},
+		{"/re/aa/x", false, "/re/:name<(?P<v>a{1,2})>/x", Params{Param{"name", "aa"}}},
+		{"/re/aaa/x", true, "", nil},
+	})
+}
+
+func Test_node_constraintConflict(t *testing.T) {
+	routes := []struct {
+		path     string
+		conflict bool
+	}{
+		{"/orders/:id<int>", false},
+		{"/orders/:id<uint>", true},
+		{"/orders/:id", true},
+		{"/orders/:id<int>/items", false},
+		{"/orders/:id<unknown>/x", true},
+		{"/bad/:id<[a-z>", true},
+		{"/bad/:id<int", true},
+		{"/bad/:id<int>x", true},
+		{"/bad/*path<int>", true},
+	}
+	testRoutes(t, routes)
+}
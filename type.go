@@ -2,4 +2,5 @@ package hapi
 
 const (
 	ServerErr = 1000
+	ArgsErr   = 1001 // the request can't be bound to the req parameter of the handler
 )
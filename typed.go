@@ -0,0 +1,44 @@
+package hapi
+
+import "reflect"
+
+// TypedHandler is a handler created by Handle, it is registered like the other handlers:
+//
+//	router.GET("/users/:id", hapi.Handle(getUser))
+type TypedHandler struct {
+	handler interface{} // the func passed to Handle
+	convert func(engine *Engine, host, path string) (HandlerFunc, reflect.Type, reflect.Type)
+}
+
+// Handle creates a handler from h, whose types are checked at compile time. The request is bound to
+// req like the req parameter of a reflective handler, Req must be a struct or struct pointer with
+// Param, Query, Header, Cookie, Body or Ctx fields. The results of h are responded by Context.Data,
+// unless h has written the response itself.
+//
+//	func getUser(ctx *hapi.Context, req *struct {
+//		Param struct {
+//			Id int64 `json:"id"`
+//		}
+//	}) (*User, error) {
+//		return findUser(req.Param.Id)
+//	}
+func Handle[Req, Resp any](h func(ctx *Context, req Req) (Resp, error)) TypedHandler {
+	return TypedHandler{
+		handler: h,
+		convert: func(engine *Engine, host, path string) (HandlerFunc, reflect.Type, reflect.Type) {
+			reqTyp := reflect.TypeOf((*Req)(nil)).Elem()
+			reqConvertFunc, _ := newReqConvertFunc(engine, reqTyp, host, path)
+			return func(ctx *Context) {
+				req, err := reqConvertFunc(ctx)
+				if err != nil {
+					handleBindingError(ctx, err)
+					return
+				}
+				resp, e := h(ctx, req.Interface().(Req))
+				if !ctx.Writer.Written() {
+					ctx.Data(resp, e)
+				}
+			}, reqTyp, reflect.TypeOf((*Resp)(nil)).Elem()
+		},
+	}
+}
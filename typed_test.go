@@ -0,0 +1,84 @@
+package hapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type typedUser struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type getTypedUserReq struct {
+	Param struct {
+		Id int `json:"id" validate:"min=1"`
+	}
+	Query struct {
+		Name string `json:"name" default:"hapi"`
+	}
+}
+
+func getTypedUser(ctx *Context, req *getTypedUserReq) (*typedUser, error) {
+	if req.Param.Id == 404 {
+		return nil, errors.New("user not found")
+	}
+	return &typedUser{Id: req.Param.Id, Name: req.Query.Name}, nil
+}
+
+func TestHandle(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", Handle(getTypedUser))
+	router.POST("/users", Handle(func(ctx *Context, req struct{ Body typedUser }) (typedUser, error) {
+		return req.Body, nil
+	}))
+	router.GET("/raw", Handle(func(ctx *Context, req *struct{}) (interface{}, error) {
+		ctx.Writer.WriteHeader(http.StatusTeapot)
+		ctx.Writer.WriteString("raw")
+		return "ignored", nil
+	}))
+
+	tests := []struct {
+		method, path, body string
+		code               int
+		resp               string
+	}{
+		{http.MethodGet, "/users/1?name=typed", "", http.StatusOK,
+			`{"code":0,"message":"success","data":{"id":1,"name":"typed"}}` + "\n"},
+		{http.MethodGet, "/users/2", "", http.StatusOK,
+			`{"code":0,"message":"success","data":{"id":2,"name":"hapi"}}` + "\n"},
+		{http.MethodGet, "/users/0", "", http.StatusBadRequest, `{"code":1001,"message":"req.Param.Id: must be at least 1",` +
+			`"data":[{"field":"req.Param.Id","location":"param","reason":"must be at least 1"}]}` + "\n"},
+		{http.MethodPost, "/users", `{"id":3,"name":"body"}`, http.StatusOK,
+			`{"code":0,"message":"success","data":{"id":3,"name":"body"}}` + "\n"},
+		{http.MethodGet, "/raw", "", http.StatusTeapot, "raw"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+		if w.Code != test.code || w.Body.String() != test.resp {
+			t.Errorf("%s %s: status code %d, body %s", test.method, test.path, w.Code, w.Body.String())
+		}
+	}
+
+	w := performRequest(router, http.MethodGet, "/users/404")
+	if w.Code != http.StatusInternalServerError || w.Body.String() != `{"code":1000,"message":"Server Error."}`+"\n" {
+		t.Errorf("error: status code %d, body %s", w.Code, w.Body.String())
+	}
+
+	route := router.Routes()[0]
+	if !strings.HasSuffix(route.Handler, ".getTypedUser") || route.Req != reflect.TypeOf(&getTypedUserReq{}) ||
+		route.Resp != reflect.TypeOf(&typedUser{}) {
+		t.Errorf("route: %+v", route)
+	}
+
+	if recv := catchPanic(func() {
+		router.GET("/bad", Handle(func(ctx *Context, req string) (string, error) { return req, nil }))
+	}); recv == nil {
+		t.Error("no panic for a string req")
+	}
+}
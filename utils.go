@@ -37,6 +37,14 @@ func nameOfFunction(f any) string {
 	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
 }
 
+func namesOfFunctions(handlers HandlersChain) []string {
+	names := make([]string, len(handlers))
+	for i, handler := range handlers {
+		names[i] = nameOfFunction(handler)
+	}
+	return names
+}
+
 func resolveAddress(addr string) string {
 	if addr == "" {
 		return ":8080"
@@ -169,3 +177,129 @@ func SetFloat(v reflect.Value, s string) error {
 	}
 	return nil
 }
+
+// compileSet returns a func doing what Set does for a value of type typ, with the kind switch done once.
+func compileSet(typ reflect.Type) func(v reflect.Value, s string) error {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		elem := typ.Elem()
+		set := compileSet(elem)
+		return func(v reflect.Value, s string) error {
+			if v.IsNil() {
+				v.Set(reflect.New(elem))
+			}
+			return set(v.Elem(), s)
+		}
+	case reflect.String:
+		return func(v reflect.Value, s string) error {
+			v.SetString(s)
+			return nil
+		}
+	case reflect.Bool:
+		return func(v reflect.Value, s string) error {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := typ.Bits()
+		return func(v reflect.Value, s string) error {
+			i, err := strconv.ParseInt(s, 10, bits)
+			if err != nil {
+				return err
+			}
+			v.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bits := typ.Bits()
+		return func(v reflect.Value, s string) error {
+			u, err := strconv.ParseUint(s, 10, bits)
+			if err != nil {
+				return err
+			}
+			v.SetUint(u)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		bits := typ.Bits()
+		return func(v reflect.Value, s string) error {
+			f, err := strconv.ParseFloat(s, bits)
+			if err != nil {
+				return err
+			}
+			v.SetFloat(f)
+			return nil
+		}
+	default:
+		return Set
+	}
+}
+
+// compileSetArray returns a func doing what SetArray does for a value of type typ.
+func compileSetArray(typ reflect.Type) func(v reflect.Value, array []string) error {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		elem := typ.Elem()
+		setArray := compileSetArray(elem)
+		return func(v reflect.Value, array []string) error {
+			if v.IsNil() {
+				v.Set(reflect.New(elem))
+			}
+			return setArray(v.Elem(), array)
+		}
+	case reflect.Slice:
+		set := compileSet(typ.Elem())
+		return func(v reflect.Value, array []string) error {
+			v.Set(reflect.MakeSlice(typ, len(array), len(array)))
+			for i, s := range array {
+				if err := set(v.Index(i), s); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Array:
+		set := compileSet(typ.Elem())
+		return func(v reflect.Value, array []string) error {
+			if len(array) > v.Len() {
+				array = array[:v.Len()]
+			}
+			for i, s := range array {
+				if err := set(v.Index(i), s); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	default:
+		set := compileSet(typ)
+		return func(v reflect.Value, array []string) error {
+			if len(array) > 0 && array[0] != "" {
+				return set(v, array[0])
+			}
+			return nil
+		}
+	}
+}
+
+// compileSetMapIndex returns a func setting the element of key, in a map of type typ, to array like SetArray does.
+// The key type of typ must be a string kind.
+func compileSetMapIndex(typ reflect.Type) func(v reflect.Value, key string, array []string) error {
+	keyType, elemType := typ.Key(), typ.Elem()
+	setArray := compileSetArray(elemType)
+	return func(v reflect.Value, key string, array []string) error {
+		elem := reflect.New(elemType).Elem()
+		if err := setArray(elem, array); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(typ))
+		}
+		v.SetMapIndex(reflect.ValueOf(key).Convert(keyType), elem)
+		return nil
+	}
+}
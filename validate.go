@@ -0,0 +1,272 @@
+package hapi
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationRule checks the value of a field tagged with `validate:"name=param"`,
+// param is empty for a rule without '='. The error returned is reported as the reason
+// the value is invalid. Pointers are dereferenced, a nil pointer is only checked by "required".
+type ValidationRule func(value reflect.Value, param string) error
+
+// builtinRules compile the param of the built-in rules for a field of type typ,
+// they return the reason a value is invalid, or "".
+var builtinRules = map[string]func(param string, typ reflect.Type) (func(reflect.Value) string, error){
+	"min":   compileLimitRule(true),
+	"max":   compileLimitRule(false),
+	"email": compileEmailRule,
+	"oneof": compileOneOfRule,
+}
+
+// Rule registers a validation rule which can then be used in the validate tags of req fields.
+// It must be called before the routes using it are registered.
+func (engine *Engine) Rule(name string, rule ValidationRule) {
+	assert1(constraintNameRegexp.MatchString(name), "invalid validation rule name '"+name+"'")
+	assert1(rule != nil, "validation rule '"+name+"' can not be nil")
+	if _, ok := builtinRules[name]; ok || name == "required" {
+		panic("validation rule '" + name + "' is built in")
+	}
+	if _, ok := engine.rules[name]; ok {
+		panic("validation rule '" + name + "' is already registered")
+	}
+	if engine.rules == nil {
+		engine.rules = make(map[string]ValidationRule)
+	}
+	engine.rules[name] = rule
+}
+
+// structValidation holds the compiled validate tags of the fields of a struct.
+type structValidation struct {
+	fields []fieldValidation
+}
+
+type fieldValidation struct {
+	index    []int  // a single index, but for a section of req within an embedded struct
+	name     string // e.g. "req.Query.Page"
+	location string
+	required bool
+	rules    []func(reflect.Value) string
+	nested   *structValidation // of a struct field having validate tags
+	section  bool              // a section of req like Query, validated as an empty struct if nil
+}
+
+// compileReqValidation compiles the validate tags of the Param, Query, Header, Cookie and Body fields of req type typ,
+// it returns nil if there is none.
+func compileReqValidation(typ reflect.Type, rules map[string]ValidationRule) *structValidation {
+	s := &structValidation{}
+	traverseTypeIndex(typ, nil, func(f reflect.StructField, index []int) {
+		switch f.Name {
+		case "Param", "Query", "Header", "Cookie", "Body":
+			if nested := compileStructValidation(
+				f.Type, "req."+f.Name, strings.ToLower(f.Name), rules, map[reflect.Type]bool{},
+			); nested != nil {
+				s.fields = append(s.fields, fieldValidation{index: index, name: "req." + f.Name, nested: nested, section: true})
+			}
+		}
+	})
+	if len(s.fields) == 0 {
+		return nil
+	}
+	return s
+}
+
+func compileStructValidation(
+	typ reflect.Type, prefix, location string, rules map[string]ValidationRule, visiting map[reflect.Type]bool,
+) *structValidation {
+	typ = indirectType(typ)
+	if typ.Kind() != reflect.Struct || visiting[typ] {
+		return nil
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	s := &structValidation{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.Anonymous && !f.IsExported() {
+			continue
+		}
+		name := prefix + "." + f.Name
+		if f.Anonymous {
+			name = prefix
+		}
+		fv := fieldValidation{index: []int{i}, name: name, location: location}
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			fv.required, fv.rules = compileFieldRules(tag, name, f.Type, rules)
+		}
+		fv.nested = compileStructValidation(f.Type, name, location, rules, visiting)
+		if fv.required || len(fv.rules) > 0 || fv.nested != nil {
+			s.fields = append(s.fields, fv)
+		}
+	}
+	if len(s.fields) == 0 {
+		return nil
+	}
+	return s
+}
+
+func compileFieldRules(
+	tag, field string, typ reflect.Type, rules map[string]ValidationRule,
+) (required bool, checks []func(reflect.Value) string) {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		if name == "required" {
+			required = true
+			continue
+		}
+		if compile := builtinRules[name]; compile != nil {
+			check, err := compile(param, indirectType(typ))
+			if err != nil {
+				panic(field + ": invalid validation rule '" + rule + "': " + err.Error())
+			}
+			checks = append(checks, check)
+		} else if fn := rules[name]; fn != nil {
+			checks = append(checks, func(v reflect.Value) string {
+				if err := fn(v, param); err != nil {
+					return err.Error()
+				}
+				return ""
+			})
+		} else {
+			panic(field + ": unknown validation rule '" + name + "'")
+		}
+	}
+	return
+}
+
+// validate appends the violations of the fields of v, a struct, to errs.
+func (s *structValidation) validate(v reflect.Value, errs []FieldError) []FieldError {
+	for _, f := range s.fields {
+		fv := fieldByIndex(v, f.index)
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr { // nil
+			if f.required {
+				errs = append(errs, FieldError{Field: f.name, Location: f.location, Reason: "is required"})
+			} else if f.section {
+				errs = f.nested.validate(reflect.New(fv.Type().Elem()).Elem(), errs)
+			}
+			continue
+		}
+		if f.required && isEmptyValue(fv) {
+			errs = append(errs, FieldError{Field: f.name, Location: f.location, Reason: "is required"})
+			continue
+		}
+		for _, check := range f.rules {
+			if reason := check(fv); reason != "" {
+				errs = append(errs, FieldError{Field: f.name, Location: f.location, Reason: reason})
+			}
+		}
+		if f.nested != nil {
+			errs = f.nested.validate(fv, errs)
+		}
+	}
+	return errs
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// compileLimitRule compiles min, or max, which limits a number or the length of a string, slice or map.
+func compileLimitRule(min bool) func(string, reflect.Type) (func(reflect.Value) string, error) {
+	return func(param string, typ reflect.Type) (func(reflect.Value) string, error) {
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", param)
+		}
+		word := "most"
+		if min {
+			word = "least"
+		}
+		exceeds := func(f float64) bool {
+			if min {
+				return f < limit
+			}
+			return f > limit
+		}
+
+		var size func(reflect.Value) float64
+		reason := "must be at " + word + " " + param
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			size = func(v reflect.Value) float64 { return float64(v.Int()) }
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			size = func(v reflect.Value) float64 { return float64(v.Uint()) }
+		case reflect.Float32, reflect.Float64:
+			size = func(v reflect.Value) float64 { return v.Float() }
+		case reflect.String:
+			size = func(v reflect.Value) float64 { return float64(utf8.RuneCountInString(v.String())) }
+			reason = "length must be at " + word + " " + param
+		case reflect.Slice, reflect.Array, reflect.Map:
+			size = func(v reflect.Value) float64 { return float64(v.Len()) }
+			reason = "length must be at " + word + " " + param
+		default:
+			return nil, fmt.Errorf("not applicable to %s", typ)
+		}
+		return func(v reflect.Value) string {
+			if exceeds(size(v)) {
+				return reason
+			}
+			return ""
+		}, nil
+	}
+}
+
+func compileEmailRule(param string, typ reflect.Type) (func(reflect.Value) string, error) {
+	if typ.Kind() != reflect.String {
+		return nil, fmt.Errorf("not applicable to %s", typ)
+	}
+	return func(v reflect.Value) string {
+		s := v.String()
+		if s == "" {
+			return "" // use required to reject an empty value
+		}
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "must be a valid email address"
+		}
+		return ""
+	}, nil
+}
+
+func compileOneOfRule(param string, typ reflect.Type) (func(reflect.Value) string, error) {
+	values := strings.Fields(param)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values")
+	}
+	for _, value := range values {
+		if err := Set(reflect.New(typ).Elem(), value); err != nil {
+			return nil, fmt.Errorf("%q is not a %s", value, typ)
+		}
+	}
+	reason := "must be one of: " + strings.Join(values, ", ")
+	return func(v reflect.Value) string {
+		s := fmt.Sprint(v)
+		for _, value := range values {
+			if s == value {
+				return ""
+			}
+		}
+		return reason
+	}, nil
+}
@@ -0,0 +1,146 @@
+package hapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidation(t *testing.T) {
+	router := New()
+	router.Rule("even", func(value reflect.Value, param string) error {
+		if value.Int()%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+	router.POST("/users/:id", func(req *struct {
+		Param struct {
+			Id int `json:"id" validate:"min=1"`
+		}
+		Query struct {
+			Page  int    `json:"page" validate:"min=1,max=100"`
+			Sort  string `json:"sort" validate:"oneof=asc desc"`
+			Size  *int   `json:"size" validate:"even"`
+			Token string `json:"token" validate:"required"`
+		}
+		Header struct {
+			Lang string `header:"Accept-Language" validate:"max=5"`
+		}
+		Body *struct {
+			Email   string   `json:"email" validate:"required,email"`
+			Tags    []string `json:"tags" validate:"max=2"`
+			Address *struct {
+				City string `json:"city" validate:"required"`
+			} `json:"address"`
+		}
+	}, resp *struct{}) {
+	})
+
+	tests := []struct {
+		path   string
+		lang   string
+		body   string
+		errors []FieldError
+	}{
+		{"/users/1?page=1&sort=asc&token=t", "en", `{"email":"a@b.c"}`, nil},
+		{"/users/0?page=101&sort=up&size=3", "zh-CN,en", `{"email":"x","tags":["a","b","c"],"address":{}}`, []FieldError{
+			{"req.Param.Id", "param", "must be at least 1"},
+			{"req.Query.Page", "query", "must be at most 100"},
+			{"req.Query.Sort", "query", "must be one of: asc, desc"},
+			{"req.Query.Size", "query", "must be even"},
+			{"req.Query.Token", "query", "is required"},
+			{"req.Header.Lang", "header", "length must be at most 5"},
+			{"req.Body.Email", "body", "must be a valid email address"},
+			{"req.Body.Tags", "body", "length must be at most 2"},
+			{"req.Body.Address.City", "body", "is required"},
+		}},
+		{"/users/1?page=1&sort=asc&token=t", "en", ``, []FieldError{
+			{"req.Body.Email", "body", "is required"},
+		}},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+		req.Header.Set("Accept-Language", test.lang)
+		var got *BindingError
+		router.BindingErrorHandler = func(c *Context, err *BindingError) {
+			got = err
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		if test.errors == nil {
+			if got != nil {
+				t.Errorf("%s: unexpected errors %v", test.path, got)
+			}
+			continue
+		}
+		if got == nil || !reflect.DeepEqual(got.Fields, test.errors) {
+			t.Errorf("%s: errors %+v", test.path, got)
+		}
+	}
+}
+
+type validationTestSections struct {
+	Query struct {
+		Id int `json:"id" validate:"required"`
+	}
+}
+
+func TestValidationEmbedded(t *testing.T) {
+	router := New()
+	router.GET("/embedded", func(req *struct{ validationTestSections }, resp *struct{}) {})
+
+	for path, code := range map[string]int{"/embedded?id=1": http.StatusOK, "/embedded": http.StatusBadRequest} {
+		if w := performRequest(router, http.MethodGet, path); w.Code != code {
+			t.Errorf("%s: status code %d, body %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestValidationRegistration(t *testing.T) {
+	router := New()
+	router.Rule("even", func(reflect.Value, string) error { return nil })
+	tests := map[string]func(){
+		"unknown rule": func() {
+			router.GET("/a", func(req *struct {
+				Query struct {
+					Page int `validate:"odd"`
+				}
+			}, resp *struct{}) {
+			})
+		},
+		"invalid min": func() {
+			router.GET("/b", func(req *struct {
+				Query struct {
+					Page int `validate:"min=x"`
+				}
+			}, resp *struct{}) {
+			})
+		},
+		"min of bool": func() {
+			router.GET("/c", func(req *struct {
+				Query struct {
+					On bool `validate:"min=1"`
+				}
+			}, resp *struct{}) {
+			})
+		},
+		"invalid oneof": func() {
+			router.GET("/d", func(req *struct {
+				Query struct {
+					Page int `validate:"oneof=1 x"`
+				}
+			}, resp *struct{}) {
+			})
+		},
+		"duplicated rule": func() { router.Rule("even", func(reflect.Value, string) error { return nil }) },
+		"built-in rule":   func() { router.Rule("min", func(reflect.Value, string) error { return nil }) },
+	}
+	for name, register := range tests {
+		if recv := catchPanic(register); recv == nil {
+			t.Errorf("no panic for %s", name)
+		}
+	}
+}
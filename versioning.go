@@ -0,0 +1,125 @@
+package hapi
+
+import (
+	"net/http"
+	"strings"
+)
+
+var default406Body = []byte(`{"code":"406","message":"version not supported."}`)
+
+// VersionSelector selects the API version requested by a request, see RouterGroup.Version.
+type VersionSelector struct {
+	// Select returns the requested version, or "" if the request doesn't ask for one.
+	Select func(*http.Request) string
+	// Vary are the request headers Select depends on, sent back in the 'Vary' response header.
+	Vary []string
+}
+
+// HeaderVersion selects the version from the value of a request header, e.g. "Api-Version: 2".
+func HeaderVersion(header string) VersionSelector {
+	return VersionSelector{
+		Select: func(req *http.Request) string {
+			return strings.TrimSpace(req.Header.Get(header))
+		},
+		Vary: []string{header},
+	}
+}
+
+// MediaTypeVersion selects the version from a vendor media type of the 'Accept' header,
+// e.g. "2" for "Accept: application/vnd.acme.v2+json" with vendor "acme".
+// An empty vendor accepts any vendor.
+func MediaTypeVersion(vendor string) VersionSelector {
+	return VersionSelector{
+		Select: func(req *http.Request) string {
+			for _, accept := range req.Header.Values("Accept") {
+				for _, mediaType := range strings.Split(accept, ",") {
+					if version := mediaTypeVersion(mediaType, vendor); version != "" {
+						return version
+					}
+				}
+			}
+			return ""
+		},
+		Vary: []string{"Accept"},
+	}
+}
+
+func mediaTypeVersion(mediaType, vendor string) string {
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if !strings.HasPrefix(mediaType, "application/vnd.") {
+		return ""
+	}
+	mediaType = mediaType[len("application/vnd."):]
+	if i := strings.IndexByte(mediaType, '+'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	i := strings.LastIndex(mediaType, ".v")
+	if i <= 0 || vendor != "" && mediaType[:i] != strings.ToLower(vendor) {
+		return ""
+	}
+	return mediaType[i+2:]
+}
+
+// Version creates a new router group whose routes are only selected for requests asking for version,
+// as returned by Engine.VersionSelector. Several versions of the same method and path can be registered
+// through different groups, requests which don't ask for a version get Engine.DefaultVersion.
+//
+//	router.Version("1").GET("/users", listUsersV1)
+//	router.Version("2").GET("/users", listUsersV2)
+func (group *RouterGroup) Version(version string, handlers ...HandlerFunc) *RouterGroup {
+	assert1(version != "", "version can not be empty")
+	return &RouterGroup{
+		Handlers: group.combineHandlers(handlers...),
+		basePath: group.basePath,
+		engine:   group.engine,
+		host:     group.host,
+		version:  version,
+	}
+}
+
+// versionedRoute holds the handlers of each version registered for the same route.
+type versionedRoute struct {
+	chains map[string]HandlersChain
+}
+
+func (t *routeTable) addVersionedRoute(host, method, path, version string, handlers HandlersChain, constraints map[string]ParamConstraint) {
+	key := method + " " + host + path
+	route := t.versioned[key]
+	if route == nil {
+		route = &versionedRoute{chains: map[string]HandlersChain{}}
+		t.addRoute(host, method, path, HandlersChain{route.handle}, constraints)
+		if t.versioned == nil {
+			t.versioned = make(map[string]*versionedRoute)
+		}
+		t.versioned[key] = route
+	}
+	if _, ok := route.chains[version]; ok {
+		panic("handlers are already registered for version '" + version + "' of path '" + host + path + "'")
+	}
+	route.chains[version] = handlers
+}
+
+// handle runs the handlers of the version requested by c.
+func (route *versionedRoute) handle(c *Context) {
+	engine := c.engine
+	for _, header := range engine.VersionSelector.Vary {
+		c.Writer.Header().Add("Vary", header)
+	}
+	version := ""
+	if engine.VersionSelector.Select != nil {
+		version = engine.VersionSelector.Select(c.Request)
+	}
+	if version == "" {
+		version = engine.DefaultVersion
+	}
+	c.handlers = route.chains[version]
+	c.index = -1
+	if c.handlers == nil {
+		serveError(c, http.StatusNotAcceptable, default406Body)
+		return
+	}
+	c.Next()
+}